@@ -9,6 +9,6 @@ require (
 )
 
 require (
-	github.com/dustin/go-heatmap v0.0.0-20180603032536-b89dbd73785a // indirect
+	github.com/dustin/go-heatmap v0.0.0-20180603032536-b89dbd73785a
 	golang.org/x/image v0.7.0
 )
@@ -0,0 +1,81 @@
+package heatmap
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/dustin/go-heatmap"
+	"github.com/dustin/go-heatmap/schemes"
+	"github.com/maxsupermanhd/go-wz/wzmap"
+	"golang.org/x/image/draw"
+)
+
+type Options struct {
+	// Scale is pixels per tile
+	Scale int
+	// Intensity is the impact size of each point on the output
+	Intensity int
+	Opacity   uint8
+	Scheme    []color.Color
+	// Background is scaled to cover the heatmap area, nil for transparent
+	Background image.Image
+}
+
+var DefaultOptions = Options{
+	Scale:     32,
+	Intensity: 20,
+	Opacity:   255,
+	Scheme:    schemes.AlphaFire,
+}
+
+// Render plots points (world units) over area (tiles), points outside of area are clamped to its edges
+func Render(area image.Rectangle, points []image.Point, o Options) image.Image {
+	if o.Scale < 1 {
+		o.Scale = 1
+	}
+	if o.Scheme == nil {
+		o.Scheme = DefaultOptions.Scheme
+	}
+	minX := float64(area.Min.X * wzmap.TileUnits)
+	minY := float64(area.Min.Y * wzmap.TileUnits)
+	maxX := float64(area.Max.X * wzmap.TileUnits)
+	maxY := float64(area.Max.Y * wzmap.TileUnits)
+	// heatmap scales points to their bounds so corners pin it to the area,
+	// its y axis goes up so points are flipped
+	data := make([]heatmap.DataPoint, 0, len(points)+2)
+	data = append(data, heatmap.P(minX, minY), heatmap.P(maxX, maxY))
+	for _, p := range points {
+		x := clamp(float64(p.X), minX, maxX)
+		y := clamp(float64(p.Y), minY, maxY)
+		data = append(data, heatmap.P(x, maxY-y+minY))
+	}
+	size := image.Rect(0, 0, area.Dx()*o.Scale, area.Dy()*o.Scale)
+	hm := heatmap.Heatmap(size, data, o.Intensity, o.Opacity, o.Scheme)
+	if o.Background == nil {
+		return hm
+	}
+	ret := image.NewRGBA(size)
+	draw.NearestNeighbor.Scale(ret, ret.Rect, o.Background, o.Background.Bounds(), draw.Over, nil)
+	draw.Draw(ret, ret.Rect, hm, image.Point{}, draw.Over)
+	return ret
+}
+
+// RenderMap plots points over scroll area of map, map preview is used as background if none is set
+func RenderMap(m *wzmap.Map, points []image.Point, o Options) image.Image {
+	area := m.ScrollArea()
+	if o.Background == nil {
+		prv := m.Preview(wzmap.DefaultPreviewOptions)
+		o.Background = prv.SubImage(area)
+	}
+	return Render(area, points, o)
+}
+
+func clamp(v, lo, hi float64) float64 {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
@@ -0,0 +1,130 @@
+package lobby
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// lobby status codes of registration response
+const (
+	StatusOK    = 200
+	StatusError = 400
+)
+
+// commands are NUL terminated 5 byte words like the game sends them
+const (
+	cmdGameID   = "gaId\x00"
+	cmdAddGame  = "addg\x00"
+	commandSize = 5
+)
+
+// HostTimeout is used for lobby responses when context has no deadline
+const HostTimeout = 10 * time.Second
+
+// ResponseError is returned when lobby answers with code other than StatusOK,
+// MOTD usually explains what is wrong (for example unreachable game port)
+type ResponseError struct {
+	Code uint32
+	MOTD string
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("lobby responded with code %d: %s", e.Code, e.MOTD)
+}
+
+// Host keeps game registered in lobby, game is removed when Host is closed
+type Host struct {
+	GameID uint32
+	Code   uint32
+	MOTD   string
+
+	mu   sync.Mutex
+	conn net.Conn
+	room Room
+}
+
+// Register requests game id, registers room and reads lobby response. Room
+// is sent with GameID assigned by lobby.
+func Register(ctx context.Context, addr string, room Room) (*Host, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	h := &Host{conn: conn, room: room}
+	if err := h.register(ctx); err != nil {
+		conn.Close()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	return h, nil
+}
+
+func (h *Host) register(ctx context.Context) error {
+	stop := watchContext(ctx, h.conn, HostTimeout)
+	defer stop()
+
+	if _, err := h.conn.Write([]byte(cmdGameID)); err != nil {
+		return err
+	}
+	if err := binary.Read(h.conn, binary.BigEndian, &h.GameID); err != nil {
+		return protocolError("read game id", err)
+	}
+	h.room.GameID = h.GameID
+	if _, err := h.conn.Write([]byte(cmdAddGame)); err != nil {
+		return err
+	}
+	wire := h.room.Encode()
+	if err := binary.Write(h.conn, binary.BigEndian, &wire); err != nil {
+		return err
+	}
+	code, motd, err := readStatus(h.conn, MaxMOTDLength)
+	if err != nil {
+		return protocolError("read status", err)
+	}
+	h.Code, h.MOTD = code, motd
+	if code != StatusOK {
+		return &ResponseError{Code: code, MOTD: motd}
+	}
+	return nil
+}
+
+// Room returns room as it was last sent to lobby
+func (h *Host) Room() Room {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.room
+}
+
+// Update sends changed room to lobby, GameID is kept
+func (h *Host) Update(room Room) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	room.GameID = h.GameID
+	wire := room.Encode()
+	h.conn.SetWriteDeadline(time.Now().Add(HostTimeout))
+	defer h.conn.SetWriteDeadline(time.Time{})
+	if err := binary.Write(h.conn, binary.BigEndian, &wire); err != nil {
+		return err
+	}
+	h.room = room
+	return nil
+}
+
+// SetPlayers updates current player count of registered room
+func (h *Host) SetPlayers(current int) error {
+	r := h.Room()
+	r.CurrentPlayers = current
+	return h.Update(r)
+}
+
+// Close removes game from lobby by closing connection
+func (h *Host) Close() error {
+	return h.conn.Close()
+}
@@ -0,0 +1,154 @@
+package lobby
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// scriptedLobby accepts single host connection and hands it to script
+func scriptedLobby(t *testing.T, script func(conn net.Conn)) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(2 * time.Second))
+		script(conn)
+	}()
+	return l.Addr().String()
+}
+
+// readRegistration reads game registration up to status response
+func readRegistration(t *testing.T, conn net.Conn, gameID uint32) (LobbyRoom, bool) {
+	var wire LobbyRoom
+	cmd := make([]byte, commandSize)
+	if _, err := io.ReadFull(conn, cmd); err != nil || string(cmd) != cmdGameID {
+		t.Errorf("first command %q %v", cmd, err)
+		return wire, false
+	}
+	binary.Write(conn, binary.BigEndian, gameID)
+	if _, err := io.ReadFull(conn, cmd); err != nil || string(cmd) != cmdAddGame {
+		t.Errorf("second command %q %v", cmd, err)
+		return wire, false
+	}
+	if err := binary.Read(conn, binary.BigEndian, &wire); err != nil {
+		t.Errorf("room: %v", err)
+		return wire, false
+	}
+	return wire, true
+}
+
+func TestHostRegister(t *testing.T) {
+	updates := make(chan Room, 1)
+	closed := make(chan error, 1)
+	addr := scriptedLobby(t, func(conn net.Conn) {
+		wire, ok := readRegistration(t, conn, 42)
+		if !ok {
+			return
+		}
+		if r := wire.Decode(); r.GameID != 42 || r.Name != "game" || r.CurrentPlayers != 1 {
+			t.Errorf("registered room %+v", r)
+		}
+		w := bufio.NewWriter(conn)
+		writeStatus(w, StatusOK, "welcome")
+		w.Flush()
+		if err := binary.Read(conn, binary.BigEndian, &wire); err != nil {
+			t.Errorf("update: %v", err)
+			return
+		}
+		updates <- wire.Decode()
+		_, err := conn.Read(make([]byte, 1))
+		closed <- err
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	h, err := Register(ctx, addr, Room{Name: "game", CurrentPlayers: 1, GameID: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	if h.GameID != 42 || h.Code != StatusOK || h.MOTD != "welcome" || h.Room().GameID != 42 {
+		t.Errorf("host id %d code %d motd %q", h.GameID, h.Code, h.MOTD)
+	}
+
+	if err := h.SetPlayers(3); err != nil {
+		t.Fatal(err)
+	}
+	if r := <-updates; r.GameID != 42 || r.Name != "game" || r.CurrentPlayers != 3 {
+		t.Errorf("updated room %+v", r)
+	}
+	if r := h.Room(); r.CurrentPlayers != 3 {
+		t.Errorf("host room %+v", r)
+	}
+
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-closed; !errors.Is(err, io.EOF) {
+		t.Errorf("lobby read %v after close, want EOF", err)
+	}
+}
+
+func TestHostRegisterErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status func(w *bufio.Writer)
+		check  func(err error) bool
+	}{
+		{"rejected", func(w *bufio.Writer) {
+			writeStatus(w, StatusError, "game port is not reachable")
+		}, func(err error) bool {
+			var re *ResponseError
+			return errors.As(err, &re) && re.Code == StatusError && re.MOTD == "game port is not reachable"
+		}},
+		{"truncated", func(w *bufio.Writer) {
+			binary.Write(w, binary.BigEndian, uint32(StatusOK))
+		}, func(err error) bool {
+			var pe *ProtocolError
+			return errors.As(err, &pe) && errors.Is(err, io.ErrUnexpectedEOF)
+		}},
+		{"long motd", func(w *bufio.Writer) {
+			binary.Write(w, binary.BigEndian, uint32(StatusOK))
+			binary.Write(w, binary.BigEndian, uint32(MaxMOTDLength+1))
+		}, func(err error) bool {
+			return errors.Is(err, ErrMOTDTooLong)
+		}},
+	}
+	for _, tt := range tests {
+		addr := scriptedLobby(t, func(conn net.Conn) {
+			if _, ok := readRegistration(t, conn, 1); ok {
+				w := bufio.NewWriter(conn)
+				tt.status(w)
+				w.Flush()
+			}
+		})
+		h, err := Register(context.Background(), addr, Room{Name: "game"})
+		if h != nil || !tt.check(err) {
+			t.Errorf("%s: got %v %v", tt.name, h, err)
+		}
+	}
+
+	// silent lobby is left when context is done
+	addr := scriptedLobby(t, func(conn net.Conn) {
+		readRegistration(t, conn, 1)
+		time.Sleep(time.Second)
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := Register(ctx, addr, Room{Name: "game"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("silent lobby: got %v, want deadline exceeded", err)
+	}
+}
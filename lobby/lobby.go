@@ -1,11 +1,14 @@
 package lobby
 
 import (
+	"bufio"
+	"context"
+	"crypto/tls"
 	"encoding/binary"
 	"errors"
-	"fmt"
 	"io"
 	"net"
+	"time"
 )
 
 type LobbyRoom struct {
@@ -43,70 +46,255 @@ type LobbyResponse struct {
 
 const LobbyAddress = "lobby.wz2100.net:9990"
 
+// MaxMOTDLength limits message of the day read from lobby
+const MaxMOTDLength = 64 * 1024
+
+var ErrMOTDTooLong = errors.New("lobby message of the day is too long")
+
+// readStatus reads status code and message that follow lobby responses
+func readStatus(r io.Reader, maxMOTD uint32) (code uint32, motd string, err error) {
+	if err = binary.Read(r, binary.BigEndian, &code); err != nil {
+		return
+	}
+	var motdlen uint32
+	if err = binary.Read(r, binary.BigEndian, &motdlen); err != nil {
+		return
+	}
+	if motdlen > maxMOTD {
+		err = ErrMOTDTooLong
+		return
+	}
+	b := make([]byte, motdlen)
+	if _, err = io.ReadFull(r, b); err != nil {
+		return
+	}
+	motd = string(b)
+	return
+}
+
+// LookupTimeout is used for lobby lookups when context has no deadline
+const LookupTimeout = 10 * time.Second
+
+// DefaultMaxRooms limits number of rooms in one list
+const DefaultMaxRooms = 4096
+
+// ErrTooManyRooms is returned when lobby lists more rooms than allowed
+var ErrTooManyRooms = errors.New("lobby listed too many rooms")
+
+// ProtocolError is returned when lobby response is malformed, truncated or
+// exceeds limits, Op tells which part of response was being read
+type ProtocolError struct {
+	Op  string
+	Err error
+}
+
+func (e *ProtocolError) Error() string {
+	return "lobby protocol error: " + e.Op + ": " + e.Err.Error()
+}
+
+func (e *ProtocolError) Unwrap() error {
+	return e.Err
+}
+
+// LookupOptions configure LobbyLookupContext, zero values use defaults
+type LookupOptions struct {
+	// Timeout is used when context has no deadline
+	Timeout time.Duration
+	// MaxMOTDLength and MaxRooms limit response size
+	MaxMOTDLength uint32
+	MaxRooms      uint32
+	// TLSConfig enables tls connection to lobby
+	TLSConfig *tls.Config
+	// Dialer is used to connect, may be nil
+	Dialer *net.Dialer
+	// Command is list command sent to lobby. When empty lookup sends command
+	// game sends and falls back to legacy one if lobby closes connection
+	// without answering it.
+	Command string
+}
+
+const (
+	// listCommand is NUL terminated command game sends
+	listCommand = "list\x00"
+	// legacyListCommand is accepted by older lobbies
+	legacyListCommand = "list\n"
+)
+
+func (o *LookupOptions) withDefaults() LookupOptions {
+	r := LookupOptions{}
+	if o != nil {
+		r = *o
+	}
+	if r.Timeout <= 0 {
+		r.Timeout = LookupTimeout
+	}
+	if r.MaxMOTDLength == 0 {
+		r.MaxMOTDLength = MaxMOTDLength
+	}
+	if r.MaxRooms == 0 {
+		r.MaxRooms = DefaultMaxRooms
+	}
+	if r.Dialer == nil {
+		r.Dialer = &net.Dialer{}
+	}
+	return r
+}
+
+// watchContext unblocks connection when ctx is done until stop is called,
+// timeout is used as connection deadline if ctx has none. Deadline of ctx is
+// not copied to connection so ctx.Err() is set whenever ctx stops it.
+func watchContext(ctx context.Context, conn net.Conn, timeout time.Duration) (stop func()) {
+	if _, ok := ctx.Deadline(); !ok {
+		conn.SetDeadline(time.Now().Add(timeout))
+	}
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.SetDeadline(time.Now())
+		case <-done:
+		}
+	}()
+	return func() {
+		close(done)
+		conn.SetDeadline(time.Time{})
+	}
+}
+
 func LobbyLookup() (LobbyResponse, error) {
 	return LobbyLookupAddr(LobbyAddress)
 }
 
 func LobbyLookupAddr(addr string) (rsp LobbyResponse, err error) {
-	conn, err := net.Dial("tcp", addr)
-	if err != nil {
-		return
+	return LobbyLookupContext(context.Background(), addr, nil)
+}
+
+// LobbyLookupContext queries lobby list, opts may be nil. Unless command is
+// set in opts, lookup is retried with legacy list command when lobby closes
+// connection before sending room count, error of first attempt is returned
+// if retry fails too. Responses over limits are not retried.
+//
+// Terminator of list command is the only protocol variant that is
+// negotiated, lobby does not announce its version before the list. Newer
+// list format is detected by flags following status, see LobbyResponse.
+func LobbyLookupContext(ctx context.Context, addr string, opts *LookupOptions) (LobbyResponse, error) {
+	o := opts.withDefaults()
+	if o.Command != "" {
+		return lookupCommand(ctx, addr, o)
 	}
-	defer conn.Close()
-	fmt.Fprintf(conn, "list\n")
+	o.Command = listCommand
+	rsp, err := lookupCommand(ctx, addr, o)
+	if !unansweredCommand(err) || ctx.Err() != nil {
+		return rsp, err
+	}
+	o.Command = legacyListCommand
+	legacy, lerr := lookupCommand(ctx, addr, o)
+	if lerr != nil {
+		return rsp, err
+	}
+	return legacy, nil
+}
 
-	var count uint32
-	err = binary.Read(conn, binary.BigEndian, &count)
+// unansweredCommand reports whether lobby ended response before room count,
+// that is how lobbies that do not know the command answer it
+func unansweredCommand(err error) bool {
+	var pe *ProtocolError
+	return errors.As(err, &pe) && pe.Op == opReadRoomCount && errors.Is(pe.Err, io.ErrUnexpectedEOF)
+}
+
+func lookupCommand(ctx context.Context, addr string, o LookupOptions) (rsp LobbyResponse, err error) {
+	var conn net.Conn
+	if o.TLSConfig != nil {
+		d := tls.Dialer{NetDialer: o.Dialer, Config: o.TLSConfig}
+		conn, err = d.DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = o.Dialer.DialContext(ctx, "tcp", addr)
+	}
 	if err != nil {
 		return
 	}
-	for i := uint32(0); i < count; i++ {
-		var room LobbyRoom
-		err = binary.Read(conn, binary.BigEndian, &room)
-		if err != nil {
-			return
-		}
-		rsp.Rooms = append(rsp.Rooms, room)
+	defer conn.Close()
+	stop := watchContext(ctx, conn, o.Timeout)
+	defer stop()
+
+	rsp, err = readList(bufio.NewReader(conn), conn, o)
+	if err != nil && ctx.Err() != nil {
+		err = ctx.Err()
 	}
+	return
+}
 
-	err = binary.Read(conn, binary.BigEndian, &rsp.Code)
-	if err != nil {
+func readList(r io.Reader, w io.Writer, o LookupOptions) (rsp LobbyResponse, err error) {
+	if _, err = io.WriteString(w, o.Command); err != nil {
 		return
 	}
-
-	var motdlen uint32
-	err = binary.Read(conn, binary.BigEndian, &motdlen)
-	if err != nil {
+	if rsp.Rooms, err = readRooms(r, o.MaxRooms, "room"); err != nil {
 		return
 	}
-	motd := make([]byte, motdlen)
-	err = binary.Read(conn, binary.BigEndian, &motd)
+	var motd string
+	rsp.Code, motd, err = readStatus(r, o.MaxMOTDLength)
 	if err != nil {
+		err = protocolError("read status", err)
 		return
 	}
-	rsp.MOTD = string(motd)
+	rsp.MOTD = motd
 
-	err = binary.Read(conn, binary.BigEndian, &rsp.Flags)
+	// older lobbies end response here, newer send flags and second list
+	err = binary.Read(r, binary.BigEndian, &rsp.Flags)
 	if errors.Is(err, io.EOF) {
 		err = nil
 		return
 	}
-
-	if (rsp.Flags & 1) == 1 {
-		rsp.Rooms = []LobbyRoom{}
+	if err != nil {
+		err = protocolError("read flags", err)
+		return
 	}
-	err = binary.Read(conn, binary.BigEndian, &count)
+	rooms, err := readRooms(r, o.MaxRooms, "second room")
 	if err != nil {
 		return
 	}
-	for i := uint32(0); i < count; i++ {
-		var room LobbyRoom
-		err = binary.Read(conn, binary.BigEndian, &room)
-		if err != nil {
+	if (rsp.Flags & 1) == 1 {
+		rsp.Rooms = rooms
+	} else {
+		if uint32(len(rsp.Rooms)+len(rooms)) > o.MaxRooms {
+			err = &ProtocolError{Op: "read rooms", Err: ErrTooManyRooms}
 			return
 		}
-		rsp.Rooms = append(rsp.Rooms, room)
+		rsp.Rooms = append(rsp.Rooms, rooms...)
 	}
-
 	return
 }
+
+// opReadRoomCount is op of error at the start of the first list
+const opReadRoomCount = "read room count"
+
+// readRooms reads list of rooms, list names it in errors ("room" for the
+// first list and "second room" for list that follows flags)
+func readRooms(r io.Reader, max uint32, list string) ([]LobbyRoom, error) {
+	var count uint32
+	if err := binary.Read(r, binary.BigEndian, &count); err != nil {
+		return nil, protocolError("read "+list+" count", err)
+	}
+	if count > max {
+		return nil, &ProtocolError{Op: "read " + list + " count", Err: ErrTooManyRooms}
+	}
+	rooms := make([]LobbyRoom, count)
+	for i := range rooms {
+		if err := binary.Read(r, binary.BigEndian, &rooms[i]); err != nil {
+			return nil, protocolError("read "+list, err)
+		}
+	}
+	return rooms, nil
+}
+
+// protocolError wraps truncated response and limit errors, network errors
+// are returned as is
+func protocolError(op string, err error) error {
+	switch {
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return &ProtocolError{Op: op, Err: io.ErrUnexpectedEOF}
+	case errors.Is(err, ErrMOTDTooLong):
+		return &ProtocolError{Op: op, Err: err}
+	}
+	return err
+}
@@ -0,0 +1,165 @@
+package lobby
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// listLobby answers each list command with respond and closes connection,
+// it records commands it received
+func listLobby(t *testing.T, respond func(cmd string, w *bufio.Writer)) (addr string, commands func() []string) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	var mu sync.Mutex
+	var got []string
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			cmd := make([]byte, commandSize)
+			if _, err := io.ReadFull(conn, cmd); err == nil {
+				mu.Lock()
+				got = append(got, string(cmd))
+				mu.Unlock()
+				w := bufio.NewWriter(conn)
+				respond(string(cmd), w)
+				w.Flush()
+			}
+			conn.Close()
+		}
+	}()
+	return l.Addr().String(), func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string{}, got...)
+	}
+}
+
+// legacyLobby serves list only for newline terminated command and drops
+// connection on anything else
+func legacyLobby(t *testing.T) (addr string, commands func() []string) {
+	t.Helper()
+	s := NewServer("old lobby")
+	s.setRoom(Room{Name: "game"}.Encode())
+	return listLobby(t, func(cmd string, w *bufio.Writer) {
+		if cmd == legacyListCommand {
+			s.writeList(w)
+		}
+	})
+}
+
+func TestLookupLegacyFallback(t *testing.T) {
+	addr, commands := legacyLobby(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	rsp, err := LobbyLookupContext(ctx, addr, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rsp.MOTD != "old lobby" || len(rsp.Rooms) != 1 {
+		t.Errorf("motd %q rooms %d", rsp.MOTD, len(rsp.Rooms))
+	}
+	if got, want := commands(), []string{listCommand, legacyListCommand}; !reflect.DeepEqual(got, want) {
+		t.Errorf("commands %q, want %q", got, want)
+	}
+}
+
+func TestLookupExplicitCommand(t *testing.T) {
+	addr, commands := legacyLobby(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	_, err := LobbyLookupContext(ctx, addr, &LookupOptions{Command: listCommand})
+	var pe *ProtocolError
+	if !errors.As(err, &pe) {
+		t.Fatalf("got %v, want protocol error", err)
+	}
+	if got := commands(); len(got) != 1 {
+		t.Errorf("explicit command retried: %q", got)
+	}
+}
+
+func TestLookupCurrentCommand(t *testing.T) {
+	addr := startServer(t, NewServer("welcome"))
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	rsp, err := LobbyLookupContext(ctx, addr, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Code != StatusOK || rsp.MOTD != "welcome" {
+		t.Errorf("code %d motd %q", rsp.Code, rsp.MOTD)
+	}
+}
+
+func TestLookupNoFallback(t *testing.T) {
+	tests := []struct {
+		name    string
+		respond func(w *bufio.Writer)
+		err     error
+	}{
+		{"too many rooms", func(w *bufio.Writer) {
+			binary.Write(w, binary.BigEndian, uint32(DefaultMaxRooms+1))
+		}, ErrTooManyRooms},
+		{"long motd", func(w *bufio.Writer) {
+			writeRooms(w, nil)
+			binary.Write(w, binary.BigEndian, uint32(StatusOK))
+			binary.Write(w, binary.BigEndian, uint32(MaxMOTDLength+1))
+		}, ErrMOTDTooLong},
+		{"truncated room", func(w *bufio.Writer) {
+			binary.Write(w, binary.BigEndian, uint32(1))
+			w.WriteString("partial room")
+		}, io.ErrUnexpectedEOF},
+		{"truncated second list", func(w *bufio.Writer) {
+			writeRooms(w, nil)
+			writeStatus(w, StatusOK, "")
+			binary.Write(w, binary.BigEndian, uint32(1))
+		}, io.ErrUnexpectedEOF},
+	}
+	for _, tt := range tests {
+		addr, commands := listLobby(t, func(cmd string, w *bufio.Writer) { tt.respond(w) })
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		_, err := LobbyLookupContext(ctx, addr, nil)
+		cancel()
+		var pe *ProtocolError
+		if !errors.As(err, &pe) || !errors.Is(err, tt.err) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.err)
+		}
+		if got := commands(); !reflect.DeepEqual(got, []string{listCommand}) {
+			t.Errorf("%s: commands %q", tt.name, got)
+		}
+	}
+}
+
+func TestLookupFallbackFirstError(t *testing.T) {
+	// lobby drops current command and sends truncated list for legacy one
+	addr, commands := listLobby(t, func(cmd string, w *bufio.Writer) {
+		if cmd == legacyListCommand {
+			writeRooms(w, nil)
+			binary.Write(w, binary.BigEndian, uint32(StatusOK))
+		}
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	_, err := LobbyLookupContext(ctx, addr, nil)
+	var pe *ProtocolError
+	if !errors.As(err, &pe) || pe.Op != "read room count" || !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("got %v, want error of first attempt", err)
+	}
+	if got, want := commands(), []string{listCommand, legacyListCommand}; !reflect.DeepEqual(got, want) {
+		t.Errorf("commands %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,196 @@
+package lobby
+
+import (
+	"bytes"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Limits are game structure limits flags of LobbyRoom.Limits, values are
+// MPFLAGS_* of the game
+type Limits uint32
+
+const (
+	LimitNoTanks Limits = 1 << iota
+	LimitNoCyborgs
+	LimitNoVTOLs
+	LimitNoUplink
+	LimitNoLassat
+	// LimitForceLimits tells that structure limits are enforced
+	LimitForceLimits
+)
+
+func (l Limits) NoTanks() bool {
+	return l&LimitNoTanks != 0
+}
+
+func (l Limits) NoCyborgs() bool {
+	return l&LimitNoCyborgs != 0
+}
+
+func (l Limits) NoVTOLs() bool {
+	return l&LimitNoVTOLs != 0
+}
+
+func (l Limits) NoUplink() bool {
+	return l&LimitNoUplink != 0
+}
+
+func (l Limits) NoLassat() bool {
+	return l&LimitNoLassat != 0
+}
+
+func (l Limits) ForceLimits() bool {
+	return l&LimitForceLimits != 0
+}
+
+// Room is decoded LobbyRoom
+type Room struct {
+	StructVersion uint32
+	Name          string
+	// Size and Flags are dwSize and dwFlags of game description
+	Size  uint32
+	Flags uint32
+	// HostAddr is host address as sent, HostIP is nil if it is not an ip address
+	HostAddr       string
+	HostIP         net.IP
+	SecondaryHosts []string
+	Port           uint16
+	MaxPlayers     int
+	CurrentPlayers int
+	UserFlags      [4]uint32
+	MapName        string
+	HostName       string
+	Version        string
+	VersionMajor   uint32
+	VersionMinor   uint32
+	Private        bool
+	Pure           bool
+	Mods           []string
+	GameID         uint32
+	Limits         Limits
+	// Extra, Future1 and Future2 are kept so encoding gives back the same room
+	Extra   [157]byte
+	Future1 uint32
+	Future2 uint32
+}
+
+func cString(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
+
+// putCString copies s into NUL terminated fixed size field, s is truncated if it does not fit
+func putCString(dst []byte, s string) {
+	for i := range dst {
+		dst[i] = 0
+	}
+	if len(s) > len(dst)-1 {
+		s = s[:len(dst)-1]
+	}
+	copy(dst, s)
+}
+
+// Addr returns host address with port for connecting to game
+func (r *Room) Addr() string {
+	return net.JoinHostPort(r.HostAddr, strconv.Itoa(int(r.Port)))
+}
+
+// Full reports whether all player slots are taken
+func (r *Room) Full() bool {
+	return r.CurrentPlayers >= r.MaxPlayers
+}
+
+func (r LobbyRoom) Decode() Room {
+	ret := Room{
+		StructVersion:  r.StructVersion,
+		Name:           cString(r.GameName[:]),
+		Size:           r.DW[0],
+		Flags:          r.DW[1],
+		HostAddr:       cString(r.HostIP[:]),
+		SecondaryHosts: []string{},
+		Port:           r.Port,
+		MaxPlayers:     int(r.MaxPlayers),
+		CurrentPlayers: int(r.CurrentPlayers),
+		UserFlags:      r.DWFlags,
+		MapName:        cString(r.MapName[:]),
+		HostName:       cString(r.HostName[:]),
+		Version:        cString(r.Version[:]),
+		VersionMajor:   r.VersionMajor,
+		VersionMinor:   r.VersionMinor,
+		Private:        r.Private != 0,
+		Pure:           r.Pure != 0,
+		Mods:           []string{},
+		GameID:         r.GameID,
+		Limits:         Limits(r.Limits),
+		Extra:          r.Extra,
+		Future1:        r.Future1,
+		Future2:        r.Future2,
+	}
+	ret.HostIP = net.ParseIP(ret.HostAddr)
+	for _, h := range r.SecHost {
+		if s := cString(h[:]); s != "" {
+			ret.SecondaryHosts = append(ret.SecondaryHosts, s)
+		}
+	}
+	for _, m := range strings.Split(cString(r.Mods[:]), ",") {
+		if m = strings.TrimSpace(m); m != "" {
+			ret.Mods = append(ret.Mods, m)
+		}
+	}
+	return ret
+}
+
+// Encode converts room back to wire struct, too long strings are truncated
+func (r Room) Encode() LobbyRoom {
+	ret := LobbyRoom{
+		StructVersion:  r.StructVersion,
+		DW:             [2]uint32{r.Size, r.Flags},
+		MaxPlayers:     uint32(r.MaxPlayers),
+		CurrentPlayers: uint32(r.CurrentPlayers),
+		DWFlags:        r.UserFlags,
+		Extra:          r.Extra,
+		Port:           r.Port,
+		VersionMajor:   r.VersionMajor,
+		VersionMinor:   r.VersionMinor,
+		ModsCount:      uint32(len(r.Mods)),
+		GameID:         r.GameID,
+		Limits:         uint32(r.Limits),
+		Future1:        r.Future1,
+		Future2:        r.Future2,
+	}
+	if r.Private {
+		ret.Private = 1
+	}
+	if r.Pure {
+		ret.Pure = 1
+	}
+	host := r.HostAddr
+	if host == "" && r.HostIP != nil {
+		host = r.HostIP.String()
+	}
+	putCString(ret.GameName[:], r.Name)
+	putCString(ret.HostIP[:], host)
+	for i := range ret.SecHost {
+		if i < len(r.SecondaryHosts) {
+			putCString(ret.SecHost[i][:], r.SecondaryHosts[i])
+		}
+	}
+	putCString(ret.MapName[:], r.MapName)
+	putCString(ret.HostName[:], r.HostName)
+	putCString(ret.Version[:], r.Version)
+	putCString(ret.Mods[:], strings.Join(r.Mods, ", "))
+	return ret
+}
+
+// DecodedRooms returns decoded Rooms of response
+func (rsp LobbyResponse) DecodedRooms() []Room {
+	ret := make([]Room, 0, len(rsp.Rooms))
+	for _, r := range rsp.Rooms {
+		ret = append(ret, r.Decode())
+	}
+	return ret
+}
@@ -0,0 +1,116 @@
+package lobby
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"reflect"
+	"testing"
+)
+
+// wireRoom lays out GAMESTRUCT field by field the way the game sends it
+func wireRoom() []byte {
+	b := &bytes.Buffer{}
+	u32 := func(v uint32) { binary.Write(b, binary.BigEndian, v) }
+	str := func(s string, n int) {
+		f := make([]byte, n)
+		copy(f, s)
+		b.Write(f)
+	}
+	u32(3)                 // StructVersion
+	str("Ranked 2v2", 64)  // GameName
+	u32(0)                 // dwSize
+	u32(0)                 // dwFlags
+	str("203.0.113.7", 40) // host
+	u32(4)                 // maxPlayers
+	u32(2)                 // currentPlayers
+	u32(0)                 // dwUserFlags[0]
+	u32(1)                 // dwUserFlags[1]
+	u32(0)                 // dwUserFlags[2]
+	u32(0)                 // dwUserFlags[3]
+	str("2001:db8::7", 40) // secondaryHosts[0]
+	str("", 40)            // secondaryHosts[1]
+	str("", 157)           // extra
+	binary.Write(b, binary.BigEndian, uint16(2100))
+	str("Sk-Rush", 40)         // mapname
+	str("NoQ", 40)             // hostname
+	str("4.4.2", 64)           // versionstring
+	str("balance, music", 255) // modlist
+	u32(4)                     // game_version_major
+	u32(1)                     // game_version_minor
+	u32(0)                     // privateGame
+	u32(1)                     // pureMap
+	u32(2)                     // Mods
+	u32(1234567)               // gameId
+	u32(0x24)                  // limits: no VTOLs, force limits
+	u32(0)                     // future3
+	u32(0)                     // future4
+	return b.Bytes()
+}
+
+func TestRoomDecode(t *testing.T) {
+	raw := wireRoom()
+	if len(raw) != binary.Size(LobbyRoom{}) {
+		t.Fatalf("wire room is %d bytes, struct is %d", len(raw), binary.Size(LobbyRoom{}))
+	}
+	var wire LobbyRoom
+	if err := binary.Read(bytes.NewReader(raw), binary.BigEndian, &wire); err != nil {
+		t.Fatal(err)
+	}
+	r := wire.Decode()
+	want := Room{
+		StructVersion:  3,
+		Name:           "Ranked 2v2",
+		HostAddr:       "203.0.113.7",
+		HostIP:         net.ParseIP("203.0.113.7"),
+		SecondaryHosts: []string{"2001:db8::7"},
+		Port:           2100,
+		MaxPlayers:     4,
+		CurrentPlayers: 2,
+		UserFlags:      [4]uint32{0, 1, 0, 0},
+		MapName:        "Sk-Rush",
+		HostName:       "NoQ",
+		Version:        "4.4.2",
+		VersionMajor:   4,
+		VersionMinor:   1,
+		Pure:           true,
+		Mods:           []string{"balance", "music"},
+		GameID:         1234567,
+		Limits:         LimitNoVTOLs | LimitForceLimits,
+	}
+	if !reflect.DeepEqual(r, want) {
+		t.Errorf("decoded\n%+v\nwant\n%+v", r, want)
+	}
+	if !r.Limits.NoVTOLs() || !r.Limits.ForceLimits() || r.Limits.NoTanks() || r.Limits.NoCyborgs() || r.Limits.NoUplink() || r.Limits.NoLassat() {
+		t.Errorf("limits %#x decoded wrong", r.Limits)
+	}
+	if r.Addr() != "203.0.113.7:2100" || r.Full() {
+		t.Errorf("addr %s full %v", r.Addr(), r.Full())
+	}
+
+	enc := r.Encode()
+	b := &bytes.Buffer{}
+	binary.Write(b, binary.BigEndian, &enc)
+	if !bytes.Equal(b.Bytes(), raw) {
+		t.Error("encoded room differs from wire room")
+	}
+}
+
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		l    Limits
+		want uint32
+	}{
+		{LimitNoTanks, 0x01},
+		{LimitNoCyborgs, 0x02},
+		{LimitNoVTOLs, 0x04},
+		{LimitNoUplink, 0x08},
+		{LimitNoLassat, 0x10},
+		{LimitForceLimits, 0x20},
+	}
+	for _, tt := range tests {
+		if uint32(tt.l) != tt.want {
+			t.Errorf("limit %#x, want %#x", uint32(tt.l), tt.want)
+		}
+	}
+}
@@ -0,0 +1,312 @@
+package lobby
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const cmdList = "list"
+
+var ErrServerClosed = errors.New("lobby server closed")
+
+// Server is a minimal lobby that speaks the same protocol as LobbyAddress.
+// Games stay listed while host connection is open and are removed when it
+// is closed.
+type Server struct {
+	// MOTD is sent with list and registration responses, use SetMOTD to
+	// change it while serving
+	MOTD string
+	// Flags are sent after list status, when non-zero list is followed by
+	// flags and second list. Rooms are sent again in second list only when
+	// flags&1 tells client to replace first list, otherwise it is empty.
+	Flags uint32
+	// Check may reject game before it is listed, error text is sent as MOTD
+	// with StatusError, may be nil
+	Check func(Room) error
+	// KeepAlive is tcp keep-alive period of host connections, zero keeps default
+	KeepAlive time.Duration
+	// Timeout limits how long client may take to send a command
+	Timeout time.Duration
+	// Log receives connection messages, may be nil
+	Log func(format string, args ...any)
+
+	mu        sync.Mutex
+	rooms     map[uint32]LobbyRoom
+	nextID    uint32
+	listeners map[net.Listener]struct{}
+	conns     map[net.Conn]struct{}
+	closed    bool
+}
+
+// NewServer creates lobby server with given message of the day
+func NewServer(motd string) *Server {
+	return &Server{MOTD: motd, Timeout: HostTimeout}
+}
+
+// SetMOTD changes message of the day of running server
+func (s *Server) SetMOTD(motd string) {
+	s.mu.Lock()
+	s.MOTD = motd
+	s.mu.Unlock()
+}
+
+func (s *Server) logf(format string, args ...any) {
+	if s.Log != nil {
+		s.Log(format, args...)
+	}
+}
+
+// ListenAndServe listens on tcp addr and serves lobby clients
+func (s *Server) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve accepts connections on l until server is closed, it always returns
+// non-nil error
+func (s *Server) Serve(l net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		l.Close()
+		return ErrServerClosed
+	}
+	if s.listeners == nil {
+		s.listeners = map[net.Listener]struct{}{}
+	}
+	s.listeners[l] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.listeners, l)
+		s.mu.Unlock()
+		l.Close()
+	}()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return ErrServerClosed
+			}
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				time.Sleep(10 * time.Millisecond)
+				continue
+			}
+			return err
+		}
+		if !s.track(conn, true) {
+			conn.Close()
+			return ErrServerClosed
+		}
+		go s.serveConn(conn)
+	}
+}
+
+func (s *Server) track(conn net.Conn, add bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if add {
+		if s.closed {
+			return false
+		}
+		if s.conns == nil {
+			s.conns = map[net.Conn]struct{}{}
+		}
+		s.conns[conn] = struct{}{}
+	} else {
+		delete(s.conns, conn)
+	}
+	return true
+}
+
+// Close stops listeners and closes all connections, removing all games
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	for l := range s.listeners {
+		l.Close()
+	}
+	for c := range s.conns {
+		c.Close()
+	}
+	s.rooms = nil
+	return nil
+}
+
+// Rooms returns currently listed games ordered by game id
+func (s *Server) Rooms() []LobbyRoom {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.roomsLocked()
+}
+
+func (s *Server) roomsLocked() []LobbyRoom {
+	ret := make([]LobbyRoom, 0, len(s.rooms))
+	for _, r := range s.rooms {
+		ret = append(ret, r)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].GameID < ret[j].GameID })
+	return ret
+}
+
+func (s *Server) newGameID() uint32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	if s.nextID == 0 {
+		s.nextID++
+	}
+	return s.nextID
+}
+
+func (s *Server) setRoom(r LobbyRoom) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.rooms == nil {
+		s.rooms = map[uint32]LobbyRoom{}
+	}
+	s.rooms[r.GameID] = r
+}
+
+func (s *Server) removeRoom(id uint32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.rooms, id)
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer s.track(conn, false)
+	defer conn.Close()
+	if tc, ok := conn.(*net.TCPConn); ok && s.KeepAlive > 0 {
+		tc.SetKeepAlive(true)
+		tc.SetKeepAlivePeriod(s.KeepAlive)
+	}
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+	var gameID uint32
+	for {
+		if s.Timeout > 0 {
+			conn.SetReadDeadline(time.Now().Add(s.Timeout))
+		}
+		cmd := make([]byte, commandSize)
+		if _, err := io.ReadFull(r, cmd); err != nil {
+			return
+		}
+		// game sends NUL terminated commands, older tools send newline
+		switch strings.TrimRight(string(cmd), "\x00\n") {
+		case cmdList:
+			if err := s.writeList(w); err != nil {
+				s.logf("lobby: list to %s: %v", conn.RemoteAddr(), err)
+			}
+			return
+		case strings.TrimRight(cmdGameID, "\x00"):
+			gameID = s.newGameID()
+			binary.Write(w, binary.BigEndian, gameID)
+			if err := w.Flush(); err != nil {
+				return
+			}
+		case strings.TrimRight(cmdAddGame, "\x00"):
+			if gameID == 0 {
+				gameID = s.newGameID()
+			}
+			s.host(conn, r, w, gameID)
+			return
+		default:
+			s.logf("lobby: unknown command %q from %s", cmd, conn.RemoteAddr())
+			return
+		}
+	}
+}
+
+// host registers game and keeps it updated until connection is closed
+func (s *Server) host(conn net.Conn, r *bufio.Reader, w *bufio.Writer, gameID uint32) {
+	var wire LobbyRoom
+	if err := binary.Read(r, binary.BigEndian, &wire); err != nil {
+		return
+	}
+	room := s.prepareRoom(conn, wire, gameID)
+	if s.Check != nil {
+		if err := s.Check(room.Decode()); err != nil {
+			writeStatus(w, StatusError, err.Error())
+			w.Flush()
+			return
+		}
+	}
+	s.setRoom(room)
+	defer s.removeRoom(gameID)
+	s.logf("lobby: game %d %q hosted from %s", gameID, cString(room.GameName[:]), conn.RemoteAddr())
+	s.mu.Lock()
+	motd := s.MOTD
+	s.mu.Unlock()
+	writeStatus(w, StatusOK, motd)
+	if err := w.Flush(); err != nil {
+		return
+	}
+	// host keeps connection open and resends game structure on changes
+	conn.SetReadDeadline(time.Time{})
+	for {
+		if err := binary.Read(r, binary.BigEndian, &wire); err != nil {
+			s.logf("lobby: game %d closed", gameID)
+			return
+		}
+		s.setRoom(s.prepareRoom(conn, wire, gameID))
+	}
+}
+
+// prepareRoom assigns game id and fills host address from connection like
+// public lobby does
+func (s *Server) prepareRoom(conn net.Conn, room LobbyRoom, gameID uint32) LobbyRoom {
+	room.GameID = gameID
+	if cString(room.HostIP[:]) == "" {
+		if host, _, err := net.SplitHostPort(conn.RemoteAddr().String()); err == nil {
+			putCString(room.HostIP[:], host)
+		}
+	}
+	return room
+}
+
+func (s *Server) writeList(w *bufio.Writer) error {
+	s.mu.Lock()
+	rooms := s.roomsLocked()
+	motd, flags := s.MOTD, s.Flags
+	s.mu.Unlock()
+	writeRooms(w, rooms)
+	writeStatus(w, StatusOK, motd)
+	if flags != 0 {
+		binary.Write(w, binary.BigEndian, flags)
+		// client appends second list unless it replaces the first one
+		if flags&1 == 0 {
+			rooms = nil
+		}
+		writeRooms(w, rooms)
+	}
+	return w.Flush()
+}
+
+func writeRooms(w *bufio.Writer, rooms []LobbyRoom) {
+	binary.Write(w, binary.BigEndian, uint32(len(rooms)))
+	for i := range rooms {
+		binary.Write(w, binary.BigEndian, &rooms[i])
+	}
+}
+
+func writeStatus(w *bufio.Writer, code uint32, motd string) {
+	binary.Write(w, binary.BigEndian, code)
+	binary.Write(w, binary.BigEndian, uint32(len(motd)))
+	w.WriteString(motd)
+}
@@ -0,0 +1,156 @@
+package lobby
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func startServer(t *testing.T, s *Server) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Serve(l)
+	t.Cleanup(func() { s.Close() })
+	return l.Addr().String()
+}
+
+// waitRooms polls lobby until rooms satisfy ok, host updates are applied asynchronously
+func waitRooms(t *testing.T, addr string, ok func([]Room) bool) []Room {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		rsp, err := LobbyLookupAddr(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rooms := rsp.DecodedRooms()
+		if ok(rooms) {
+			return rooms
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("unexpected rooms %+v", rooms)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestServerEndToEnd(t *testing.T) {
+	addr := startServer(t, NewServer("welcome"))
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	h, err := Register(ctx, addr, Room{Name: "test game", MapName: "Sk-Rush", MaxPlayers: 4, CurrentPlayers: 1, Port: 2100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	if h.Code != StatusOK || h.MOTD != "welcome" || h.GameID == 0 {
+		t.Errorf("registration code %d motd %q game id %d", h.Code, h.MOTD, h.GameID)
+	}
+
+	rsp, err := LobbyLookupAddr(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Code != StatusOK || rsp.MOTD != "welcome" || len(rsp.Rooms) != 1 {
+		t.Fatalf("list code %d motd %q rooms %d", rsp.Code, rsp.MOTD, len(rsp.Rooms))
+	}
+	r := rsp.Rooms[0].Decode()
+	if r.Name != "test game" || r.MapName != "Sk-Rush" || r.GameID != h.GameID || r.CurrentPlayers != 1 {
+		t.Errorf("listed room %+v", r)
+	}
+	// lobby fills host address from connection
+	if r.HostAddr != "127.0.0.1" || r.Addr() != "127.0.0.1:2100" {
+		t.Errorf("host address %q", r.HostAddr)
+	}
+
+	if err := h.SetPlayers(3); err != nil {
+		t.Fatal(err)
+	}
+	waitRooms(t, addr, func(rooms []Room) bool {
+		return len(rooms) == 1 && rooms[0].CurrentPlayers == 3 && rooms[0].GameID == h.GameID
+	})
+
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+	waitRooms(t, addr, func(rooms []Room) bool { return len(rooms) == 0 })
+}
+
+func TestServerFlags(t *testing.T) {
+	for _, flags := range []uint32{0, 1, 2} {
+		s := NewServer("")
+		s.Flags = flags
+		addr := startServer(t, s)
+		h, err := Register(context.Background(), addr, Room{Name: "game"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rsp, err := LobbyLookupAddr(addr)
+		h.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rsp.Flags != flags || len(rsp.Rooms) != 1 {
+			t.Errorf("flags %d: got flags %d and %d rooms", flags, rsp.Flags, len(rsp.Rooms))
+		}
+	}
+}
+
+func TestServerCheck(t *testing.T) {
+	s := NewServer("")
+	s.Check = func(r Room) error {
+		if r.Name == "bad" {
+			return errors.New("game port is not reachable")
+		}
+		return nil
+	}
+	addr := startServer(t, s)
+	_, err := Register(context.Background(), addr, Room{Name: "bad"})
+	var re *ResponseError
+	if !errors.As(err, &re) || re.Code != StatusError || re.MOTD != "game port is not reachable" {
+		t.Fatalf("got %v, want response error", err)
+	}
+	if rooms := s.Rooms(); len(rooms) != 0 {
+		t.Errorf("rejected game is listed: %d rooms", len(rooms))
+	}
+}
+
+func TestServerSetMOTD(t *testing.T) {
+	s := NewServer("first")
+	addr := startServer(t, s)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				s.SetMOTD("second")
+			}
+		}
+	}()
+	h, err := Register(context.Background(), addr, Room{Name: "game"})
+	close(stop)
+	<-done
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	if h.MOTD != "first" && h.MOTD != "second" {
+		t.Errorf("registration motd %q", h.MOTD)
+	}
+	rsp, err := LobbyLookupAddr(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rsp.MOTD != "second" {
+		t.Errorf("list motd %q", rsp.MOTD)
+	}
+}
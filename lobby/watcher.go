@@ -0,0 +1,186 @@
+package lobby
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// EventType tells what changed in lobby between two polls
+type EventType int
+
+const (
+	EventRoomOpened EventType = iota
+	EventPlayersChanged
+	EventRoomClosed
+	EventMOTDChanged
+	// EventError is sent when lookup fails, watcher retries with backoff
+	EventError
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventRoomOpened:
+		return "room opened"
+	case EventPlayersChanged:
+		return "players changed"
+	case EventRoomClosed:
+		return "room closed"
+	case EventMOTDChanged:
+		return "motd changed"
+	case EventError:
+		return "error"
+	}
+	return "unknown"
+}
+
+// Event is a single lobby change. Room is set for room events, Previous is
+// room state from previous poll for EventPlayersChanged and EventRoomClosed.
+type Event struct {
+	Type     EventType
+	Room     Room
+	Previous Room
+	MOTD     string
+	Err      error
+}
+
+// Watcher polls lobby and reports changes of rooms by GameID
+type Watcher struct {
+	Addr string
+	// Interval between successful polls, DefaultWatchInterval if not positive
+	Interval time.Duration
+	// MaxBackoff limits delay after failed polls, delay doubles on each failure
+	MaxBackoff time.Duration
+	// Lookup is used to query lobby, LobbyLookupContext is used if nil
+	Lookup func(ctx context.Context, addr string) (LobbyResponse, error)
+	// Buffer is size of events channel
+	Buffer int
+}
+
+const DefaultWatchInterval = 30 * time.Second
+
+// NewWatcher creates watcher of lobby at addr polling every DefaultWatchInterval
+func NewWatcher(addr string) *Watcher {
+	return &Watcher{
+		Addr:       addr,
+		Interval:   DefaultWatchInterval,
+		MaxBackoff: 5 * time.Minute,
+		Buffer:     16,
+	}
+}
+
+// lookup returns when ctx is done even if Lookup does not watch ctx itself
+func (w *Watcher) lookup(ctx context.Context) (LobbyResponse, error) {
+	lookup := w.Lookup
+	if lookup == nil {
+		lookup = func(ctx context.Context, addr string) (LobbyResponse, error) {
+			return LobbyLookupContext(ctx, addr, nil)
+		}
+	}
+	type result struct {
+		rsp LobbyResponse
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		rsp, err := lookup(ctx, w.Addr)
+		done <- result{rsp, err}
+	}()
+	select {
+	case r := <-done:
+		return r.rsp, r.err
+	case <-ctx.Done():
+		return LobbyResponse{}, ctx.Err()
+	}
+}
+
+// Watch starts polling and returns channel of events. First successful poll
+// reports all listed rooms as opened. Channel is closed after ctx is done.
+func (w *Watcher) Watch(ctx context.Context) <-chan Event {
+	ch := make(chan Event, w.Buffer)
+	go w.run(ctx, ch)
+	return ch
+}
+
+func (w *Watcher) run(ctx context.Context, ch chan<- Event) {
+	defer close(ch)
+	send := func(e Event) bool {
+		select {
+		case ch <- e:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	rooms := map[uint32]Room{}
+	motd := ""
+	interval := w.Interval
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	delay := interval
+	for {
+		rsp, err := w.lookup(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			if !send(Event{Type: EventError, Err: err}) {
+				return
+			}
+			delay *= 2
+			if w.MaxBackoff > 0 && delay > w.MaxBackoff {
+				delay = w.MaxBackoff
+			}
+		} else {
+			delay = interval
+			for _, e := range diffRooms(rooms, rsp.DecodedRooms()) {
+				if !send(e) {
+					return
+				}
+			}
+			if rsp.MOTD != motd {
+				motd = rsp.MOTD
+				if !send(Event{Type: EventMOTDChanged, MOTD: motd}) {
+					return
+				}
+			}
+		}
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// diffRooms updates known rooms with listed ones and returns events in
+// order of listing, closed rooms are reported last
+func diffRooms(known map[uint32]Room, listed []Room) []Event {
+	ret := []Event{}
+	seen := map[uint32]bool{}
+	for _, r := range listed {
+		seen[r.GameID] = true
+		prev, ok := known[r.GameID]
+		known[r.GameID] = r
+		if !ok {
+			ret = append(ret, Event{Type: EventRoomOpened, Room: r})
+		} else if prev.CurrentPlayers != r.CurrentPlayers {
+			ret = append(ret, Event{Type: EventPlayersChanged, Room: r, Previous: prev})
+		}
+	}
+	closed := []uint32{}
+	for id := range known {
+		if !seen[id] {
+			closed = append(closed, id)
+		}
+	}
+	sort.Slice(closed, func(i, j int) bool { return closed[i] < closed[j] })
+	for _, id := range closed {
+		ret = append(ret, Event{Type: EventRoomClosed, Room: known[id], Previous: known[id]})
+		delete(known, id)
+	}
+	return ret
+}
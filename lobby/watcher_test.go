@@ -0,0 +1,87 @@
+package lobby
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWatcherEvents(t *testing.T) {
+	room := func(id uint32, players int) LobbyRoom {
+		return Room{GameID: id, Name: "game", CurrentPlayers: players}.Encode()
+	}
+	polls := []LobbyResponse{
+		{MOTD: "hi", Rooms: []LobbyRoom{room(1, 1)}},
+		{MOTD: "hi", Rooms: []LobbyRoom{room(1, 2), room(2, 1)}},
+		{MOTD: "bye", Rooms: []LobbyRoom{room(2, 1)}},
+	}
+	var n int32
+	w := NewWatcher("")
+	w.Interval = time.Millisecond
+	w.Lookup = func(ctx context.Context, addr string) (LobbyResponse, error) {
+		i := int(atomic.AddInt32(&n, 1)) - 1
+		if i >= len(polls) {
+			i = len(polls) - 1
+		}
+		return polls[i], nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := w.Watch(ctx)
+	want := []struct {
+		t       EventType
+		id      uint32
+		players int
+	}{
+		{EventRoomOpened, 1, 1},
+		{EventMOTDChanged, 0, 0},
+		{EventPlayersChanged, 1, 2},
+		{EventRoomOpened, 2, 1},
+		{EventRoomClosed, 1, 2},
+		{EventMOTDChanged, 0, 0},
+	}
+	for _, ww := range want {
+		e := <-ch
+		if e.Type != ww.t || e.Room.GameID != ww.id || e.Room.CurrentPlayers != ww.players {
+			t.Fatalf("got %v room %d players %d, want %v room %d players %d", e.Type, e.Room.GameID, e.Room.CurrentPlayers, ww.t, ww.id, ww.players)
+		}
+	}
+}
+
+func TestWatcherDefaultInterval(t *testing.T) {
+	var n int32
+	w := &Watcher{Lookup: func(ctx context.Context, addr string) (LobbyResponse, error) {
+		atomic.AddInt32(&n, 1)
+		return LobbyResponse{}, nil
+	}}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	for range w.Watch(ctx) {
+	}
+	if got := atomic.LoadInt32(&n); got != 1 {
+		t.Errorf("zero interval polled %d times", got)
+	}
+}
+
+func TestWatcherCancelDuringLookup(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	w := NewWatcher("")
+	// lookup ignores ctx
+	w.Lookup = func(ctx context.Context, addr string) (LobbyResponse, error) {
+		<-block
+		return LobbyResponse{}, nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := w.Watch(ctx)
+	cancel()
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Error("event after cancel")
+		}
+	case <-time.After(time.Second):
+		t.Error("watcher did not stop while lookup was running")
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"image"
+	"image/png"
+	"log"
+	"os"
+
+	mapsdatabase "github.com/maxsupermanhd/go-wz/maps-database"
+	"github.com/maxsupermanhd/go-wz/stat"
+	"github.com/maxsupermanhd/go-wz/wzmap"
+)
+
+var (
+	in       = flag.String("i", "", "Path to map archive to analyze")
+	out      = flag.String("o", "-", "Path to write json report to, - for stdout")
+	statsdir = flag.String("stats", "", "Optional path to stats directory for structure and propulsion types")
+	oilReach = flag.Int("oilReach", wzmap.DefaultAnalyzeOptions.OilReach, "Radius in tiles around start position where oil counts as player's")
+	overlay  = flag.String("overlay", "", "Path to write travel distance overlay (png) from start positions")
+	class    = flag.String("class", "ground", "Propulsion class of overlay, ground, hover or vtol")
+	scale    = flag.Int("scale", 8, "Overlay pixels per tile")
+)
+
+type report struct {
+	Info     *mapsdatabase.MapInfo `json:"info"`
+	Analysis *wzmap.Analysis       `json:"analysis"`
+}
+
+func main() {
+	log.SetFlags(0)
+	flag.Parse()
+	if *in == "" {
+		flag.Usage()
+		os.Exit(1)
+	}
+	o := wzmap.DefaultAnalyzeOptions
+	o.OilReach = *oilReach
+	if *statsdir != "" {
+		s, err := stat.LoadStats(*statsdir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		o.Stats = s
+	}
+	b, err := os.ReadFile(*in)
+	if err != nil {
+		log.Fatal(err)
+	}
+	info, a, err := mapsdatabase.AnalyzeMap(b, o)
+	if err != nil {
+		log.Fatal(err)
+	}
+	rb, err := json.MarshalIndent(report{Info: info, Analysis: a}, "", "\t")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *out == "-" {
+		os.Stdout.Write(rb)
+	} else if err := os.WriteFile(*out, rb, 0644); err != nil {
+		log.Fatal(err)
+	}
+	if *overlay != "" {
+		writeOverlay(b, a)
+	}
+}
+
+func writeOverlay(b []byte, a *wzmap.Analysis) {
+	m, err := wzmap.Open(b)
+	if err != nil {
+		log.Fatal(err)
+	}
+	c := map[string]wzmap.PathClass{
+		"ground": wzmap.PathGround,
+		"hover":  wzmap.PathHover,
+		"vtol":   wzmap.PathVTOL,
+	}
+	pc, ok := c[*class]
+	if !ok {
+		log.Fatalf("Unknown propulsion class %q", *class)
+	}
+	starts := []image.Point{}
+	for _, s := range a.Slots {
+		if s.Start != nil {
+			starts = append(starts, *s.Start)
+		}
+	}
+	img := m.PathOverlay(pc, starts...)
+	if *scale > 1 {
+		img = wzmap.ScaleImage(img, *scale)
+	}
+	buf := bytes.NewBuffer([]byte{})
+	if err := png.Encode(buf, img); err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*overlay, buf.Bytes(), 0644); err != nil {
+		log.Fatal(err)
+	}
+}
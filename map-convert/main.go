@@ -0,0 +1,41 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/maxsupermanhd/go-wz/wzmap"
+)
+
+var (
+	in   = flag.String("i", "", "Path to map archive to convert")
+	out  = flag.String("o", "", "Path to write converted map archive to")
+	name = flag.String("name", "", "Optional new map name")
+)
+
+func main() {
+	log.SetFlags(0)
+	flag.Parse()
+	if *in == "" || *out == "" {
+		flag.Usage()
+		os.Exit(1)
+	}
+	m, err := wzmap.OpenFile(*in)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if m.Legacy {
+		log.Printf("Converting legacy objects of %q to JSON", m.Name)
+	}
+	if *name != "" {
+		m.Name = *name
+		// level.json of legacy maps is named after map
+		if m.Level != nil {
+			m.Level.Name = *name
+		}
+	}
+	if err := m.WriteFile(*out); err != nil {
+		log.Fatal(err)
+	}
+}
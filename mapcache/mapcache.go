@@ -0,0 +1,373 @@
+package mapcache
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	mapsdatabase "github.com/maxsupermanhd/go-wz/maps-database"
+)
+
+var (
+	ErrHashMismatch = errors.New("downloaded map does not match its hash")
+	ErrInvalidHash  = errors.New("map hash is not 64 hex characters")
+)
+
+type entry struct {
+	Blob     string    `json:"blob"`
+	Size     int64     `json:"size"`
+	ETag     string    `json:"etag,omitempty"`
+	Modified string    `json:"modified,omitempty"`
+	Type     string    `json:"type,omitempty"`
+	Fetched  time.Time `json:"fetched"`
+	Accessed time.Time `json:"accessed"`
+}
+
+// Cache stores responses of map services on disk. Bodies are stored once by
+// their sha256 so map archives are found by map hash without any requests.
+type Cache struct {
+	Dir string
+	// TTL is how long entries are used without revalidation
+	TTL time.Duration
+	// MaxSize limits total size of stored blobs in bytes, 0 for unlimited
+	MaxSize int64
+
+	mu      sync.Mutex
+	entries map[string]*entry
+}
+
+const indexFile = "index.json"
+
+// mapKeyPrefix keys entries of map archives stored by PutMap so eviction accounts for them
+const mapKeyPrefix = "map:"
+
+// maxBodySize limits responses stored by transport, larger ones are passed
+// through without caching
+const maxBodySize = 64 << 20
+
+func Open(dir string, ttl time.Duration, maxSize int64) (*Cache, error) {
+	if err := os.MkdirAll(path.Join(dir, "blobs"), 0755); err != nil {
+		return nil, err
+	}
+	c := &Cache{Dir: dir, TTL: ttl, MaxSize: maxSize, entries: map[string]*entry{}}
+	b, err := os.ReadFile(path.Join(dir, indexFile))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(b, &c.entries); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// validHash reports whether sum is lowercase hex encoded sha256, hashes come
+// from replays and index file so they are checked before building paths
+func validHash(sum string) bool {
+	if len(sum) != sha256.Size*2 {
+		return false
+	}
+	for _, c := range sum {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *Cache) blobPath(sum string) (string, error) {
+	if !validHash(sum) {
+		return "", ErrInvalidHash
+	}
+	return path.Join(c.Dir, "blobs", sum), nil
+}
+
+func (c *Cache) saveIndex() error {
+	b, err := json.Marshal(c.entries)
+	if err != nil {
+		return err
+	}
+	tmp := path.Join(c.Dir, indexFile+".tmp")
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path.Join(c.Dir, indexFile))
+}
+
+func (c *Cache) writeBlob(b []byte) (string, error) {
+	h := sha256.Sum256(b)
+	sum := hex.EncodeToString(h[:])
+	p, err := c.blobPath(sum)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(p); err == nil {
+		return sum, nil
+	}
+	tmp := p + ".tmp"
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return "", err
+	}
+	return sum, os.Rename(tmp, p)
+}
+
+// get returns copy of entry and its body, entries with missing blobs are
+// dropped. Access time is saved so eviction order survives restarts. It has
+// to be called with mu held, returned entry is safe to use without it.
+func (c *Cache) get(key string) (*entry, []byte) {
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, nil
+	}
+	p, err := c.blobPath(e.Blob)
+	if err != nil {
+		delete(c.entries, key)
+		return nil, nil
+	}
+	b, err := os.ReadFile(p)
+	if err != nil {
+		delete(c.entries, key)
+		return nil, nil
+	}
+	e.Accessed = time.Now()
+	// failing to save only makes eviction order stale
+	c.saveIndex()
+	cp := *e
+	return &cp, b
+}
+
+func (c *Cache) put(key string, b []byte, e *entry) error {
+	sum, err := c.writeBlob(b)
+	if err != nil {
+		return err
+	}
+	e.Blob = sum
+	e.Size = int64(len(b))
+	e.Accessed = time.Now()
+	c.entries[key] = e
+	c.evict()
+	return c.saveIndex()
+}
+
+// evict removes least recently accessed entries until blobs fit in MaxSize
+func (c *Cache) evict() {
+	if c.MaxSize <= 0 {
+		return
+	}
+	sizes := map[string]int64{}
+	refs := map[string]int{}
+	for _, e := range c.entries {
+		sizes[e.Blob] = e.Size
+		refs[e.Blob]++
+	}
+	total := int64(0)
+	for _, s := range sizes {
+		total += s
+	}
+	keys := make([]string, 0, len(c.entries))
+	for k := range c.entries {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return c.entries[keys[i]].Accessed.Before(c.entries[keys[j]].Accessed)
+	})
+	for _, k := range keys {
+		if total <= c.MaxSize {
+			break
+		}
+		e := c.entries[k]
+		delete(c.entries, k)
+		refs[e.Blob]--
+		if refs[e.Blob] == 0 {
+			if p, err := c.blobPath(e.Blob); err == nil {
+				os.Remove(p)
+			}
+			total -= e.Size
+		}
+	}
+}
+
+// Map returns map archive by map hash if it was stored before, hashes that
+// are not hex encoded sha256 are never found
+func (c *Cache) Map(hash string) ([]byte, bool) {
+	hash = strings.ToLower(hash)
+	if !validHash(hash) {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, b := c.get(mapKeyPrefix + hash); b != nil {
+		return b, true
+	}
+	// blob may be stored by transport under its download url
+	p, err := c.blobPath(hash)
+	if err != nil {
+		return nil, false
+	}
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return nil, false
+	}
+	return b, true
+}
+
+// PutMap stores map archive, it is keyed by its hash
+func (c *Cache) PutMap(b []byte) (string, error) {
+	h := sha256.Sum256(b)
+	hash := hex.EncodeToString(h[:])
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return hash, c.put(mapKeyPrefix+hash, b, &entry{Fetched: time.Now()})
+}
+
+// FetchMapBlob returns map archive from cache or downloads it with client,
+// downloaded archive is checked to match hash
+func (c *Cache) FetchMapBlob(ctx context.Context, cl *mapsdatabase.Client, hash string) ([]byte, error) {
+	hash = strings.ToLower(hash)
+	if !validHash(hash) {
+		return nil, ErrInvalidHash
+	}
+	if b, ok := c.Map(hash); ok {
+		return b, nil
+	}
+	b, err := cl.FetchMapBlob(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	// corrupt downloads are not stored
+	h := sha256.Sum256(b)
+	if hex.EncodeToString(h[:]) != hash {
+		return nil, ErrHashMismatch
+	}
+	if _, err := c.PutMap(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// HTTPClient returns client that caches GET responses, use it as HTTPClient
+// of mapsdatabase.Client or phobos.Client
+func (c *Cache) HTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: c.Transport(nil),
+	}
+}
+
+// Transport wraps next (http.DefaultTransport if nil) so GET responses are
+// served from cache while fresh, revalidated with ETag or Last-Modified when
+// stale and served stale when network is unavailable
+func (c *Cache) Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &transport{c: c, next: next}
+}
+
+type transport struct {
+	c    *Cache
+	next http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return t.next.RoundTrip(req)
+	}
+	key := req.URL.String()
+	t.c.mu.Lock()
+	e, cached := t.c.get(key)
+	t.c.mu.Unlock()
+	if e != nil && time.Since(e.Fetched) < t.c.TTL {
+		return cachedResponse(req, e, cached), nil
+	}
+	out := req
+	if e != nil {
+		out = req.Clone(req.Context())
+		if e.ETag != "" {
+			out.Header.Set("If-None-Match", e.ETag)
+		}
+		if e.Modified != "" {
+			out.Header.Set("If-Modified-Since", e.Modified)
+		}
+	}
+	resp, err := t.next.RoundTrip(out)
+	if err != nil {
+		if e != nil && req.Context().Err() == nil {
+			return cachedResponse(req, e, cached), nil
+		}
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotModified && e != nil {
+		resp.Body.Close()
+		t.c.mu.Lock()
+		if stored, ok := t.c.entries[key]; ok {
+			stored.Fetched = time.Now()
+			err = t.c.saveIndex()
+		}
+		t.c.mu.Unlock()
+		return cachedResponse(req, e, cached), err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize+1))
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	if len(b) > maxBodySize {
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(b), resp.Body), resp.Body}
+		return resp, nil
+	}
+	resp.Body.Close()
+	t.c.mu.Lock()
+	err = t.c.put(key, b, &entry{
+		ETag:     resp.Header.Get("ETag"),
+		Modified: resp.Header.Get("Last-Modified"),
+		Type:     resp.Header.Get("Content-Type"),
+		Fetched:  time.Now(),
+	})
+	t.c.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(b))
+	return resp, nil
+}
+
+func cachedResponse(req *http.Request, e *entry, b []byte) *http.Response {
+	h := http.Header{}
+	if e.Type != "" {
+		h.Set("Content-Type", e.Type)
+	}
+	if e.ETag != "" {
+		h.Set("ETag", e.ETag)
+	}
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        h,
+		Body:          io.NopCloser(bytes.NewReader(b)),
+		ContentLength: int64(len(b)),
+		Request:       req,
+	}
+}
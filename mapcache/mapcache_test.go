@@ -0,0 +1,161 @@
+package mapcache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	mapsdatabase "github.com/maxsupermanhd/go-wz/maps-database"
+)
+
+func hashOf(b []byte) string {
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
+
+func TestCacheMap(t *testing.T) {
+	dir := t.TempDir()
+	c, err := Open(dir, time.Hour, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive := []byte("map archive")
+	hash, err := c.PutMap(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash != hashOf(archive) {
+		t.Errorf("stored as %s", hash)
+	}
+	if b, ok := c.Map(strings.ToUpper(hash)); !ok || string(b) != "map archive" {
+		t.Errorf("hit: got %q %v", b, ok)
+	}
+	if _, ok := c.Map(hashOf([]byte("other"))); ok {
+		t.Error("miss found")
+	}
+
+	// stored maps survive reopening
+	c, err = Open(dir, time.Hour, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.Map(hash); !ok {
+		t.Error("not found after reopen")
+	}
+}
+
+func TestCacheEviction(t *testing.T) {
+	dir := t.TempDir()
+	c, err := Open(dir, time.Hour, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, _ := c.PutMap([]byte("first map!"))
+	b, _ := c.PutMap([]byte("second map"))
+	// access time of first map is kept across reopen so second one is older
+	c.Map(a)
+	c, err = Open(dir, time.Hour, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.PutMap([]byte("third map!")); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.Map(a); !ok {
+		t.Error("recently used map evicted")
+	}
+	if _, ok := c.Map(b); ok {
+		t.Error("least recently used map kept")
+	}
+	if _, err := os.Stat(path.Join(dir, "blobs", b)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("evicted blob left on disk: %v", err)
+	}
+}
+
+func TestCacheInvalidHash(t *testing.T) {
+	dir := t.TempDir()
+	c, err := Open(dir, time.Hour, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(path.Join(dir, "secret"), []byte("secret"), 0644)
+	for _, hash := range []string{"", "../secret", "../index.json", strings.Repeat("g", 64), strings.Repeat("a", 63)} {
+		if b, ok := c.Map(hash); ok {
+			t.Errorf("%q: found %q", hash, b)
+		}
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("requested %s", r.URL)
+	}))
+	defer srv.Close()
+	cl := mapsdatabase.NewClient()
+	cl.APIURL = srv.URL + "/"
+	if _, err := c.FetchMapBlob(context.Background(), cl, "../secret"); !errors.Is(err, ErrInvalidHash) {
+		t.Errorf("got %v, want invalid hash", err)
+	}
+
+	// index entries pointing outside of blobs are dropped
+	os.WriteFile(path.Join(dir, indexFile), []byte(`{"map:x": {"blob": "../secret", "size": 6}}`), 0644)
+	c, err = Open(dir, time.Hour, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.mu.Lock()
+	e, b := c.get("map:x")
+	c.mu.Unlock()
+	if e != nil || b != nil {
+		t.Errorf("got %+v %q", e, b)
+	}
+}
+
+func TestTransport(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("body"))
+	}))
+	defer srv.Close()
+	c, err := Open(t.TempDir(), time.Hour, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cl := c.HTTPClient(time.Second)
+	get := func() string {
+		t.Helper()
+		resp, err := cl.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+	if got := get(); got != "body" || requests != 1 {
+		t.Errorf("miss: got %q after %d requests", got, requests)
+	}
+	if got := get(); got != "body" || requests != 1 {
+		t.Errorf("fresh hit: got %q after %d requests", got, requests)
+	}
+	c.TTL = 0
+	if got := get(); got != "body" || requests != 2 {
+		t.Errorf("revalidated: got %q after %d requests", got, requests)
+	}
+}
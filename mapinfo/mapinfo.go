@@ -0,0 +1,217 @@
+package mapinfo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"strings"
+	"sync"
+
+	mapsdatabase "github.com/maxsupermanhd/go-wz/maps-database"
+	"github.com/maxsupermanhd/go-wz/phobos"
+	"github.com/maxsupermanhd/go-wz/wzmap"
+)
+
+var (
+	ErrNotFound      = errors.New("map info not found in any source")
+	ErrNoEmbedded    = errors.New("no embedded map")
+	ErrUnknownSource = errors.New("unknown map info source")
+)
+
+// Info is map metadata common to all sources
+type Info struct {
+	Hash    string
+	Name    string
+	Players int
+	Tileset string
+	Authors []string
+	License string
+	Width   int
+	Height  int
+	// Scroll is scroll limits in tiles, whole map if source does not know them
+	Scroll     image.Rectangle
+	OilWells   int
+	Scavengers int
+	// Source is name of source info came from
+	Source string
+}
+
+type Source interface {
+	Name() string
+	// Resolve returns info of map with hash, embedded may be nil
+	Resolve(ctx context.Context, hash string, embedded *wzmap.Map) (*Info, error)
+}
+
+// Embedded reads info from map embedded in replay
+type Embedded struct{}
+
+func (Embedded) Name() string {
+	return "embedded"
+}
+
+func (Embedded) Resolve(ctx context.Context, hash string, m *wzmap.Map) (*Info, error) {
+	if m == nil {
+		return nil, ErrNoEmbedded
+	}
+	// only header, level and objects are read, map is not analyzed
+	ret := &Info{
+		Hash:       hash,
+		Name:       m.Name,
+		Players:    m.Players(),
+		Tileset:    m.Tileset(),
+		Authors:    []string{},
+		Width:      m.Width,
+		Height:     m.Height,
+		Scroll:     m.ScrollArea(),
+		OilWells:   len(m.OilResources()),
+		Scavengers: m.Scavengers(),
+	}
+	if ret.Players == 0 {
+		ret.Players = len(m.StartPositions())
+	}
+	if m.Level != nil {
+		if m.Level.Name != "" {
+			ret.Name = m.Level.Name
+		}
+		ret.Authors = append(ret.Authors, m.Level.Authors...)
+		ret.License = m.Level.License
+	}
+	return ret, nil
+}
+
+type MapsDatabase struct {
+	Client *mapsdatabase.Client
+}
+
+func (MapsDatabase) Name() string {
+	return "mapsdatabase"
+}
+
+func (s MapsDatabase) Resolve(ctx context.Context, hash string, _ *wzmap.Map) (*Info, error) {
+	c := s.Client
+	if c == nil {
+		c = mapsdatabase.NewClient()
+	}
+	i, err := c.FetchMapInfo(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	ret := fromMapsDatabase(i)
+	ret.Hash = hash
+	return ret, nil
+}
+
+func fromMapsDatabase(i *mapsdatabase.MapInfo) *Info {
+	return &Info{
+		Hash:       i.Download.Hash,
+		Name:       i.Name,
+		Players:    i.Slots,
+		Tileset:    i.Tileset,
+		Authors:    i.Authors,
+		License:    i.License,
+		Width:      i.Size.W,
+		Height:     i.Size.H,
+		Scroll:     image.Rect(0, 0, i.Size.W, i.Size.H),
+		OilWells:   i.OilWells,
+		Scavengers: i.Scavs,
+	}
+}
+
+type Phobos struct {
+	Client *phobos.Client
+}
+
+func (Phobos) Name() string {
+	return "phobos"
+}
+
+func (s Phobos) Resolve(ctx context.Context, hash string, _ *wzmap.Map) (*Info, error) {
+	c := s.Client
+	if c == nil {
+		c = phobos.NewClient()
+	}
+	i, err := c.FetchInfo(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	ret := &Info{
+		Hash:       hash,
+		Name:       i.MapName,
+		Players:    i.MapPlayers,
+		License:    i.MapLicense,
+		OilWells:   i.MapOilwells,
+		Scavengers: i.MapScavengers,
+		Scroll:     image.Rect(i.MapScrX1, i.MapScrY1, i.MapScrX2, i.MapScrY2),
+	}
+	if i.MapAuthor != "" {
+		ret.Authors = []string{i.MapAuthor}
+	}
+	fmt.Sscanf(i.MapSize, "%dx%d", &ret.Width, &ret.Height)
+	if ret.Scroll.Empty() {
+		ret.Scroll = image.Rect(0, 0, ret.Width, ret.Height)
+	}
+	return ret, nil
+}
+
+// SourcesByName builds sources from comma separated names (embedded,
+// mapsdatabase, phobos) keeping their order, clients may be nil for defaults
+func SourcesByName(names string, md *mapsdatabase.Client, ph *phobos.Client) ([]Source, error) {
+	ret := []Source{}
+	for _, n := range strings.Split(names, ",") {
+		switch strings.TrimSpace(n) {
+		case "":
+		case "embedded":
+			ret = append(ret, Embedded{})
+		case "mapsdatabase":
+			ret = append(ret, MapsDatabase{Client: md})
+		case "phobos":
+			ret = append(ret, Phobos{Client: ph})
+		default:
+			return nil, fmt.Errorf("%w: %q", ErrUnknownSource, n)
+		}
+	}
+	return ret, nil
+}
+
+// Resolver tries sources in order and remembers resolved info by hash. Use
+// clients with mapcache transport to keep info between runs.
+type Resolver struct {
+	Sources []Source
+	// Errors of last failed resolve by source name
+	Errors map[string]error
+
+	mu    sync.Mutex
+	cache map[string]*Info
+}
+
+func NewResolver(sources ...Source) *Resolver {
+	return &Resolver{Sources: sources, cache: map[string]*Info{}}
+}
+
+func (r *Resolver) Resolve(ctx context.Context, hash string, embedded *wzmap.Map) (*Info, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cache == nil {
+		r.cache = map[string]*Info{}
+	}
+	if i, ok := r.cache[hash]; ok && hash != "" {
+		return i, nil
+	}
+	errs := map[string]error{}
+	for _, s := range r.Sources {
+		i, err := s.Resolve(ctx, hash, embedded)
+		if err != nil {
+			errs[s.Name()] = err
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			continue
+		}
+		i.Source = s.Name()
+		r.cache[hash] = i
+		return i, nil
+	}
+	r.Errors = errs
+	return nil, ErrNotFound
+}
@@ -0,0 +1,86 @@
+package mapsdatabase
+
+import (
+	"github.com/maxsupermanhd/go-wz/wzmap"
+)
+
+type balanceRange = struct {
+	Eq  bool `json:"eq"`
+	Min int  `json:"min"`
+	Max int  `json:"max"`
+}
+
+// AnalyzeMap builds MapInfo for .wz archive the way database does for published maps
+func AnalyzeMap(b []byte, o wzmap.AnalyzeOptions) (*MapInfo, *wzmap.Analysis, error) {
+	m, err := wzmap.Open(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	a := wzmap.Analyze(m, o)
+	info := MapInfoFromAnalysis(m, a)
+	info.Download.Hash = wzmap.Hash(b)
+	info.Download.Size = len(b)
+	return info, a, nil
+}
+
+// MapInfoFromAnalysis fills MapInfo fields that can be derived from map itself
+func MapInfoFromAnalysis(m *wzmap.Map, a *wzmap.Analysis) *MapInfo {
+	info := &MapInfo{
+		Name:     m.Name,
+		Slots:    len(a.Slots),
+		Tileset:  m.Tileset(),
+		Authors:  []string{},
+		Scavs:    a.Scavengers,
+		OilWells: a.OilWells,
+		Hq:       [][2]int{},
+	}
+	if p := m.Players(); p > 0 {
+		info.Slots = p
+	}
+	if m.Level != nil {
+		if m.Level.Name != "" {
+			info.Name = m.Level.Name
+		}
+		info.Authors = append(info.Authors, m.Level.Authors...)
+		info.License = m.Level.License
+		info.Created = m.Level.Created
+	}
+	info.Size.W = m.Width
+	info.Size.H = m.Height
+	count := func(f func(s wzmap.SlotAnalysis) int) balanceRange {
+		r := balanceRange{Eq: true}
+		for i, s := range a.Slots {
+			v := f(s)
+			if i == 0 {
+				r.Min, r.Max = v, v
+				continue
+			}
+			if v < r.Min {
+				r.Min = v
+			}
+			if v > r.Max {
+				r.Max = v
+			}
+		}
+		r.Eq = r.Min == r.Max
+		return r
+	}
+	byType := func(t string) func(s wzmap.SlotAnalysis) int {
+		return func(s wzmap.SlotAnalysis) int { return s.StructureTypes[t] }
+	}
+	info.Player.Units = count(func(s wzmap.SlotAnalysis) int { return s.Units })
+	info.Player.Structs = count(func(s wzmap.SlotAnalysis) int { return s.Structures })
+	info.Player.ResourceExtr = count(byType(wzmap.StructResourceExtract))
+	info.Player.PwrGen = count(byType(wzmap.StructPowerGenerator))
+	info.Player.RegFact = count(byType(wzmap.StructFactory))
+	info.Player.VtolFact = count(byType(wzmap.StructVTOLFactory))
+	info.Player.CyborgFact = count(byType(wzmap.StructCyborgFactory))
+	info.Player.ResearchCent = count(byType(wzmap.StructResearch))
+	info.Player.DefStruct = count(byType(wzmap.StructDefense))
+	for _, s := range a.Slots {
+		if s.HQ != nil {
+			info.Hq = append(info.Hq, [2]int{s.HQ.X, s.HQ.Y})
+		}
+	}
+	return info
+}
@@ -0,0 +1,205 @@
+package mapsdatabase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultAPIURL      = "https://maps.wz2100.net/api/v1/"
+	DefaultReleasesURL = "https://github.com/Warzone2100/"
+)
+
+var (
+	ErrNotFound    = errors.New("map not found")
+	ErrRateLimited = errors.New("rate limited")
+)
+
+// StatusError is returned when server answers with not 200 status code, it
+// matches ErrNotFound and ErrRateLimited with errors.Is
+type StatusError struct {
+	URL        string
+	StatusCode int
+	// RetryAfter is parsed from Retry-After header of rate limited responses
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s returned status %d", e.URL, e.StatusCode)
+}
+
+func (e *StatusError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	}
+	return false
+}
+
+type Client struct {
+	// APIURL is base of api, map endpoints are resolved relative to it
+	APIURL string
+	// ReleasesURL is base of map repositories, blobs are downloaded from
+	// maps-<repo>/releases/download/<path> relative to it
+	ReleasesURL string
+	HTTPClient  *http.Client
+	UserAgent   string
+	// Retries is how many times failed request is repeated on network
+	// errors, 5xx and rate limit responses
+	Retries int
+	// RetryDelay is delay before first retry, it doubles with each attempt
+	RetryDelay time.Duration
+}
+
+func NewClient() *Client {
+	return &Client{
+		APIURL:      DefaultAPIURL,
+		ReleasesURL: DefaultReleasesURL,
+		HTTPClient:  defaultClient,
+		UserAgent:   "go-wz",
+		Retries:     2,
+		RetryDelay:  time.Second,
+	}
+}
+
+func joinURL(base, def, p string) string {
+	if base == "" {
+		base = def
+	}
+	return strings.TrimSuffix(base, "/") + "/" + p
+}
+
+func (c *Client) apiURL(p string) string {
+	return joinURL(c.APIURL, DefaultAPIURL, p)
+}
+
+// get performs request with retries, caller must close body of returned response
+func (c *Client) get(ctx context.Context, u string) (*http.Response, error) {
+	cl := c.HTTPClient
+	if cl == nil {
+		cl = defaultClient
+	}
+	delay := c.RetryDelay
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return nil, err
+		}
+		if c.UserAgent != "" {
+			req.Header.Set("User-Agent", c.UserAgent)
+		}
+		resp, err := cl.Do(req)
+		retry := false
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			retry = true
+		} else if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			serr := &StatusError{URL: u, StatusCode: resp.StatusCode}
+			if s, perr := strconv.Atoi(resp.Header.Get("Retry-After")); perr == nil {
+				serr.RetryAfter = time.Duration(s) * time.Second
+			}
+			err = serr
+			retry = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+			if serr.RetryAfter > delay {
+				delay = serr.RetryAfter
+			}
+		} else {
+			return resp, nil
+		}
+		if !retry || attempt >= c.Retries {
+			return nil, err
+		}
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+		delay *= 2
+	}
+}
+
+func (c *Client) getJSON(ctx context.Context, u string, v any) error {
+	resp, err := c.get(ctx, u)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func (c *Client) getPNG(ctx context.Context, u string) (image.Image, error) {
+	resp, err := c.get(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return png.Decode(resp.Body)
+}
+
+func (c *Client) FetchMapInfo(ctx context.Context, hash string) (*MapInfo, error) {
+	var info MapInfo
+	if err := c.getJSON(ctx, c.apiURL("maps/"+hash+"/info.json"), &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// BlobURL returns download address of map archive described by info
+func (c *Client) BlobURL(info *MapInfo) string {
+	return joinURL(c.ReleasesURL, DefaultReleasesURL, "maps-"+info.Download.Repo+"/releases/download/"+info.Download.Path)
+}
+
+func (c *Client) FetchMapBlob(ctx context.Context, hash string) ([]byte, error) {
+	info, err := c.FetchMapInfo(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	return c.FetchMapBlobByInfo(ctx, info)
+}
+
+func (c *Client) FetchMapBlobByInfo(ctx context.Context, info *MapInfo) ([]byte, error) {
+	resp, err := c.get(ctx, c.BlobURL(info))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+func (c *Client) FetchMapPreview(ctx context.Context, hash string) (image.Image, error) {
+	return c.getPNG(ctx, c.apiURL("maps/"+hash+"/preview.png"))
+}
+
+func (c *Client) FetchMapTerrain(ctx context.Context, hash string) (image.Image, error) {
+	return c.getPNG(ctx, c.apiURL("maps/"+hash+"/terrain.png"))
+}
+
+// resolveLink resolves pagination link against api url so relative links work
+func (c *Client) resolveLink(link string) (string, error) {
+	base, err := url.Parse(c.apiURL(""))
+	if err != nil {
+		return "", err
+	}
+	l, err := url.Parse(link)
+	if err != nil {
+		return "", err
+	}
+	return base.ResolveReference(l).String(), nil
+}
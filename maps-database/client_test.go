@@ -0,0 +1,178 @@
+package mapsdatabase
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testClient points client at local stand-in server with fast retries
+func testClient(t *testing.T, h http.Handler) *Client {
+	t.Helper()
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	c := NewClient()
+	c.APIURL = srv.URL + "/api/v1/"
+	c.ReleasesURL = srv.URL + "/releases/"
+	c.RetryDelay = time.Millisecond
+	return c
+}
+
+func TestClientRetry(t *testing.T) {
+	var calls int32
+	c := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"name": "Sk-Rush", "slots": 4, "author": "Pumpkin Studios"}`))
+	}))
+	info, err := c.FetchMapInfo(context.Background(), "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Name != "Sk-Rush" || info.Slots != 4 || !reflect.DeepEqual(info.Authors, []string{"Pumpkin Studios"}) {
+		t.Errorf("info %+v", info)
+	}
+	if n := atomic.LoadInt32(&calls); n != 3 {
+		t.Errorf("%d requests, want 3", n)
+	}
+
+	// retries are exhausted
+	atomic.StoreInt32(&calls, 0)
+	c.Retries = 1
+	_, err = c.FetchMapInfo(context.Background(), "abc")
+	var serr *StatusError
+	if !errors.As(err, &serr) || serr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("got %v, want status error", err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Errorf("%d requests, want 2", n)
+	}
+}
+
+func TestClientStatusErrors(t *testing.T) {
+	var calls int32
+	c := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		switch r.URL.Path {
+		case "/api/v1/maps/limited/info.json":
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case "/api/v1/maps/broken/info.json":
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	c.Retries = 0
+	ctx := context.Background()
+
+	_, err := c.FetchMapInfo(ctx, "limited")
+	var serr *StatusError
+	if !errors.Is(err, ErrRateLimited) || errors.Is(err, ErrNotFound) || !errors.As(err, &serr) || serr.RetryAfter != 7*time.Second {
+		t.Errorf("rate limited: got %v", err)
+	}
+
+	// not found is not retried
+	c.Retries = 2
+	atomic.StoreInt32(&calls, 0)
+	if _, err := c.FetchMapInfo(ctx, "missing"); !errors.Is(err, ErrNotFound) || errors.Is(err, ErrRateLimited) {
+		t.Errorf("not found: got %v", err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("not found requested %d times", n)
+	}
+	if _, err := c.FetchMapInfo(ctx, "broken"); errors.Is(err, ErrNotFound) || errors.Is(err, ErrRateLimited) || !errors.As(err, &serr) || serr.StatusCode != http.StatusForbidden {
+		t.Errorf("forbidden: got %v", err)
+	}
+}
+
+func TestClientRetryCanceled(t *testing.T) {
+	c := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	c.RetryDelay = time.Hour
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := c.FetchMapInfo(ctx, "abc"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want deadline exceeded", err)
+	}
+}
+
+func TestClientPagination(t *testing.T) {
+	pages := map[string]string{
+		"": `{"links": {"self": "full.json", "next": "full.json?page=2"}, "maps": [
+			{"name": "A", "author": ["x", "y"]},
+			{"name": "B", "author": "z"}
+		]}`,
+		"2": `{"links": {"next": "/api/v1/full.json?page=3"}, "maps": [{"name": "C"}]}`,
+		"3": `{"links": {}, "maps": [{"name": "D"}]}`,
+	}
+	var failPage atomic.Value
+	failPage.Store("none")
+	c := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/full.json" {
+			http.NotFound(w, r)
+			return
+		}
+		p := r.URL.Query().Get("page")
+		if p == failPage.Load().(string) {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(pages[p]))
+	}))
+	c.Retries = 0
+	ctx := context.Background()
+
+	maps, err := c.FetchAllMaps(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, m := range maps {
+		names = append(names, m.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"A", "B", "C", "D"}) {
+		t.Errorf("maps %v", names)
+	}
+	if !reflect.DeepEqual(maps[0].Authors, []string{"x", "y"}) || !reflect.DeepEqual(maps[1].Authors, []string{"z"}) {
+		t.Errorf("authors %v %v", maps[0].Authors, maps[1].Authors)
+	}
+
+	// failed page is reported with link to resume from
+	failPage.Store("3")
+	maps, err = c.FetchAllMaps(ctx)
+	var perr *PageError
+	if !errors.As(err, &perr) || len(maps) != 3 {
+		t.Fatalf("got %d maps and %v, want 3 maps and page error", len(maps), err)
+	}
+	failPage.Store("none")
+	rest, err := c.FetchMapsFrom(ctx, perr.Link)
+	if err != nil || len(rest) != 1 || rest[0].Name != "D" {
+		t.Errorf("resumed from %s: %v %v", perr.Link, rest, err)
+	}
+}
+
+func TestFetchMapBlob(t *testing.T) {
+	c := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/maps/abc/info.json":
+			w.Write([]byte(`{"name": "Sk-Rush", "download": {"repo": "4p", "path": "v1/4c-Rush.wz"}}`))
+		case "/releases/maps-4p/releases/download/v1/4c-Rush.wz":
+			w.Write([]byte("archive"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	b, err := c.FetchMapBlob(context.Background(), "abc")
+	if err != nil || string(b) != "archive" {
+		t.Errorf("blob %q %v", b, err)
+	}
+}
@@ -1,10 +1,9 @@
 package mapsdatabase
 
 import (
+	"context"
 	"encoding/json"
 	"image"
-	"image/png"
-	"io"
 	"net/http"
 	"time"
 )
@@ -95,8 +94,13 @@ func (f *MapInfo) UnmarshalJSON(data []byte) error {
 	switch v := aliased.Author.(type) {
 	case string:
 		aliased.Authors = []string{v}
-	case []string:
-		aliased.Authors = v
+	case []any:
+		aliased.Authors = []string{}
+		for _, a := range v {
+			if s, ok := a.(string); ok {
+				aliased.Authors = append(aliased.Authors, s)
+			}
+		}
 	}
 	return nil
 }
@@ -121,24 +125,21 @@ var defaultClient = &http.Client{
 	Timeout: 5 * time.Second,
 }
 
+func clientFor(cl *http.Client) *Client {
+	if cl == nil {
+		cl = defaultClient
+	}
+	c := NewClient()
+	c.HTTPClient = cl
+	return c
+}
+
 func FetchMapInfo(hash string) (*MapInfo, error) {
 	return FetchMapInfoWithClient(hash, defaultClient)
 }
 
 func FetchMapInfoWithClient(hash string, cl *http.Client) (*MapInfo, error) {
-	if cl == nil {
-		cl = defaultClient
-	}
-	ir, err := cl.Get("https://maps.wz2100.net/api/v1/maps/" + hash + "/info.json")
-	if err != nil {
-		return nil, err
-	}
-	var info MapInfo
-	err = json.NewDecoder(ir.Body).Decode(&info)
-	if err != nil {
-		return nil, err
-	}
-	return &info, nil
+	return clientFor(cl).FetchMapInfo(context.Background(), hash)
 }
 
 func FetchMapBlob(hash string) ([]byte, error) {
@@ -146,18 +147,7 @@ func FetchMapBlob(hash string) ([]byte, error) {
 }
 
 func FetchMapBlobWithClient(hash string, cl *http.Client) ([]byte, error) {
-	if cl == nil {
-		cl = defaultClient
-	}
-	info, err := FetchMapInfoWithClient(hash, cl)
-	if err != nil {
-		return nil, err
-	}
-	br, err := cl.Get("https://github.com/Warzone2100/maps-" + info.Download.Repo + "/releases/download/" + info.Download.Path)
-	if err != nil {
-		return nil, err
-	}
-	return io.ReadAll(br.Body)
+	return clientFor(cl).FetchMapBlob(context.Background(), hash)
 }
 
 func FetchMapPreview(hash string) (image.Image, error) {
@@ -165,14 +155,7 @@ func FetchMapPreview(hash string) (image.Image, error) {
 }
 
 func FetchMapPreviewWithClient(hash string, cl *http.Client) (image.Image, error) {
-	if cl == nil {
-		cl = defaultClient
-	}
-	br, err := cl.Get("https://maps.wz2100.net/api/v1/maps/" + hash + "/preview.png")
-	if err != nil {
-		return nil, err
-	}
-	return png.Decode(br.Body)
+	return clientFor(cl).FetchMapPreview(context.Background(), hash)
 }
 
 func FetchMapTerrain(hash string) (image.Image, error) {
@@ -180,14 +163,7 @@ func FetchMapTerrain(hash string) (image.Image, error) {
 }
 
 func FetchMapTerrainWithClient(hash string, cl *http.Client) (image.Image, error) {
-	if cl == nil {
-		cl = defaultClient
-	}
-	br, err := cl.Get("https://maps.wz2100.net/api/v1/maps/" + hash + "/terrain.png")
-	if err != nil {
-		return nil, err
-	}
-	return png.Decode(br.Body)
+	return clientFor(cl).FetchMapTerrain(context.Background(), hash)
 }
 
 type mapsPaginated struct {
@@ -199,28 +175,7 @@ type mapsPaginated struct {
 }
 
 func FetchAllMapsWithClient(cl *http.Client) ([]MapInfo, error) {
-	if cl == nil {
-		cl = defaultClient
-	}
-	next := "https://maps.wz2100.net/api/v1/full.json"
-	ret := []MapInfo{}
-	for {
-		ir, err := cl.Get(next)
-		if err != nil {
-			return nil, err
-		}
-		var page mapsPaginated
-		err = json.NewDecoder(ir.Body).Decode(&page)
-		if err != nil {
-			return nil, err
-		}
-		ret = append(ret, page.Maps...)
-		if page.Links.Next == "" {
-			break
-		}
-		next = page.Links.Next
-	}
-	return ret, nil
+	return clientFor(cl).FetchAllMaps(context.Background())
 }
 
 func FetchAllMaps() ([]MapInfo, error) {
@@ -0,0 +1,449 @@
+package mapsdatabase
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrBlobHashMismatch = errors.New("map blob does not match download hash")
+	ErrUnsafePath       = errors.New("map download path leaves mirror directory")
+)
+
+// Mirror keeps copy of database in Dir laid out like api paths:
+// api/v1/full.json, api/v1/maps/<hash>/{info.json,preview.png,terrain.png}
+// and map archives at releases/maps-<repo>/releases/download/<path>.
+// Progress of unfinished sync is kept in sync directory.
+type Mirror struct {
+	Dir    string
+	Client *Client
+	// Workers is number of maps synced at once
+	Workers int
+	// Prune removes maps that are no longer listed by database, maps that
+	// failed to sync are kept
+	Prune bool
+	// Log receives progress messages, may be nil
+	Log func(format string, args ...any)
+}
+
+type SyncResult struct {
+	Total   int
+	Updated int
+	Skipped int
+	Pruned  int
+	// Failed maps errors by hash
+	Failed map[string]error
+}
+
+type mapsPaginatedRaw struct {
+	Links struct {
+		Self string `json:"self"`
+		Next string `json:"next"`
+	} `json:"links"`
+	Maps []json.RawMessage `json:"maps"`
+}
+
+func NewMirror(dir string) *Mirror {
+	return &Mirror{Dir: dir, Client: NewClient(), Workers: 4}
+}
+
+func (m *Mirror) logf(format string, args ...any) {
+	if m.Log != nil {
+		m.Log(format, args...)
+	}
+}
+
+func (m *Mirror) apiPath(p ...string) string {
+	return path.Join(append([]string{m.Dir, "api", "v1"}, p...)...)
+}
+
+// blobPath returns where map archive is stored, repo and path come from
+// remote index so they are checked to stay inside of mirror directory
+func (m *Mirror) blobPath(info *MapInfo) (string, error) {
+	repo, p := info.Download.Repo, info.Download.Path
+	if repo == "" || strings.ContainsAny(repo, "/\\") || strings.Contains(repo, "..") || strings.Contains(p, "\\") {
+		return "", ErrUnsafePath
+	}
+	p = path.Clean("/" + p)
+	if p == "/" {
+		return "", ErrUnsafePath
+	}
+	root := path.Join(m.Dir, "releases")
+	ret := path.Join(root, "maps-"+repo, "releases", "download", p)
+	if !strings.HasPrefix(ret, root+"/") {
+		return "", ErrUnsafePath
+	}
+	return ret, nil
+}
+
+func (c *Client) fetchRaw(ctx context.Context, u string) ([]byte, error) {
+	resp, err := c.get(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+// syncState is checkpoint of unfinished sync kept in mirror directory
+type syncState struct {
+	APIURL string `json:"apiURL"`
+	// Next is link of first page that was not synced
+	Next string `json:"next"`
+}
+
+func (m *Mirror) statePath() string {
+	return path.Join(m.Dir, "sync", "state.json")
+}
+
+// listedPath is file with mirrored maps of synced pages, one json per line,
+// it becomes index when last page is synced
+func (m *Mirror) listedPath() string {
+	return path.Join(m.Dir, "sync", "listed.jsonl")
+}
+
+func (m *Mirror) saveState(st syncState) error {
+	b, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(m.statePath(), b)
+}
+
+// Sync downloads index page by page and every map whose hash is not mirrored
+// yet. Progress is saved after each page, when sync fails or ctx is cancelled
+// next Sync continues from page that was not finished. Result counts only
+// maps of pages synced by this call.
+func (m *Mirror) Sync(ctx context.Context) (*SyncResult, error) {
+	c := m.Client
+	if c == nil {
+		c = NewClient()
+	}
+	res := &SyncResult{Failed: map[string]error{}}
+	start := ""
+	var st syncState
+	if b, err := os.ReadFile(m.statePath()); err == nil && json.Unmarshal(b, &st) == nil && st.APIURL == c.APIURL && st.Next != "" {
+		start = st.Next
+		m.logf("Resuming sync from %s", start)
+	} else if err := os.Remove(m.listedPath()); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return res, err
+	}
+	if err := os.MkdirAll(path.Dir(m.listedPath()), 0755); err != nil {
+		return res, err
+	}
+	listed, err := os.OpenFile(m.listedPath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return res, err
+	}
+	defer listed.Close()
+
+	it := c.Pages(ctx, start)
+	for it.Next() {
+		ok := m.syncPage(ctx, c, it.Maps(), it.RawMaps(), res)
+		if err := ctx.Err(); err != nil {
+			// page is synced again on resume, mirrored maps are skipped
+			return res, m.saveState(syncState{APIURL: c.APIURL, Next: it.Link()})
+		}
+		w := bufio.NewWriter(listed)
+		for _, r := range ok {
+			b := &bytes.Buffer{}
+			if err := json.Compact(b, r); err != nil {
+				return res, err
+			}
+			b.WriteByte('\n')
+			w.Write(b.Bytes())
+		}
+		if err := w.Flush(); err != nil {
+			return res, err
+		}
+		if err := m.saveState(syncState{APIURL: c.APIURL, Next: it.NextLink()}); err != nil {
+			return res, err
+		}
+	}
+	if err := it.Err(); err != nil {
+		if serr := m.saveState(syncState{APIURL: c.APIURL, Next: it.Link()}); serr != nil {
+			return res, serr
+		}
+		return res, err
+	}
+	listed.Close()
+
+	seen, err := m.writeIndex()
+	if err != nil {
+		return res, err
+	}
+	m.logf("Index has %d mirrored maps", len(seen))
+	if m.Prune {
+		entries, err := os.ReadDir(m.apiPath("maps"))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return res, err
+		}
+		for _, e := range entries {
+			if _, failed := res.Failed[e.Name()]; seen[e.Name()] || failed {
+				continue
+			}
+			if info, err := m.Info(e.Name()); err == nil {
+				if p, err := m.blobPath(info); err == nil {
+					os.Remove(p)
+				}
+			}
+			if err := os.RemoveAll(m.apiPath("maps", e.Name())); err != nil {
+				return res, err
+			}
+			res.Pruned++
+		}
+	}
+	return res, os.RemoveAll(path.Dir(m.statePath()))
+}
+
+// syncPage mirrors maps of one page and returns raw info of maps that are
+// present in mirror, failed maps are recorded in res. Failed maps that were
+// mirrored by previous sync are returned with their previous info so they
+// stay listed and are not pruned.
+func (m *Mirror) syncPage(ctx context.Context, c *Client, maps []MapInfo, raw []json.RawMessage, res *SyncResult) []json.RawMessage {
+	workers := m.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, workers)
+	ok := make([]bool, len(maps))
+	for i := range maps {
+		info := &maps[i]
+		hash := info.Download.Hash
+		if hash == "" || strings.ContainsAny(hash, "/\\.") {
+			continue
+		}
+		res.Total++
+		if m.mirrored(info) {
+			res.Skipped++
+			ok[i] = true
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, info *MapInfo) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			err := m.syncMap(ctx, c, info, raw[i])
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				res.Failed[info.Download.Hash] = err
+				m.logf("Failed %s %q: %v", info.Download.Hash, info.Name, err)
+				return
+			}
+			res.Updated++
+			ok[i] = true
+			m.logf("Mirrored %s %q", info.Download.Hash, info.Name)
+		}(i, info)
+	}
+	wg.Wait()
+	ret := []json.RawMessage{}
+	for i, r := range raw {
+		if ok[i] {
+			ret = append(ret, r)
+			continue
+		}
+		if _, failed := res.Failed[maps[i].Download.Hash]; !failed {
+			continue
+		}
+		if b, err := os.ReadFile(m.apiPath("maps", maps[i].Download.Hash, "info.json")); err == nil {
+			ret = append(ret, b)
+		}
+	}
+	return ret
+}
+
+// writeIndex streams synced pages into full.json. Index is written last and
+// lists only maps present in mirror, failed maps without previous copy are
+// not listed until next sync gets them. Maps of page synced twice after resume are listed once.
+func (m *Mirror) writeIndex() (map[string]bool, error) {
+	in, err := os.Open(m.listedPath())
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+	p := m.apiPath("full.json")
+	if err := os.MkdirAll(path.Dir(p), 0755); err != nil {
+		return nil, err
+	}
+	tmp := p + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	w.WriteString(`{"links":{"self":"","next":""},"maps":[`)
+	seen := map[string]bool{}
+	r := bufio.NewReader(in)
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var info MapInfo
+			if err := json.Unmarshal(line, &info); err != nil {
+				return nil, err
+			}
+			if !seen[info.Download.Hash] {
+				if len(seen) > 0 {
+					w.WriteByte(',')
+				}
+				seen[info.Download.Hash] = true
+				w.Write(bytes.TrimSpace(line))
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	w.WriteString("]}")
+	if err := w.Flush(); err != nil {
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+	return seen, os.Rename(tmp, p)
+}
+
+// mirrored reports whether map info, images and verified blob are present
+func (m *Mirror) mirrored(info *MapInfo) bool {
+	old, err := m.Info(info.Download.Hash)
+	if err != nil || old.Download.Hash != info.Download.Hash || old.Download.Path != info.Download.Path {
+		return false
+	}
+	blob, err := m.blobPath(info)
+	if err != nil {
+		return false
+	}
+	for _, p := range []string{
+		m.apiPath("maps", info.Download.Hash, "preview.png"),
+		m.apiPath("maps", info.Download.Hash, "terrain.png"),
+		blob,
+	} {
+		if _, err := os.Stat(p); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *Mirror) syncMap(ctx context.Context, c *Client, info *MapInfo, raw json.RawMessage) error {
+	hash := info.Download.Hash
+	blobPath, err := m.blobPath(info)
+	if err != nil {
+		return err
+	}
+	blob, err := c.FetchMapBlobByInfo(ctx, info)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(blob)
+	if !strings.EqualFold(hex.EncodeToString(sum[:]), hash) {
+		return ErrBlobHashMismatch
+	}
+	files := map[string][]byte{blobPath: blob}
+	for _, name := range []string{"preview.png", "terrain.png"} {
+		b, err := c.fetchRaw(ctx, c.apiURL("maps/"+hash+"/"+name))
+		if err != nil {
+			return err
+		}
+		files[m.apiPath("maps", hash, name)] = b
+	}
+	for p, b := range files {
+		if err := writeFileAtomic(p, b); err != nil {
+			return err
+		}
+	}
+	// info is written last, it marks map as mirrored
+	return writeFileAtomic(m.apiPath("maps", hash, "info.json"), raw)
+}
+
+func writeFileAtomic(p string, b []byte) error {
+	if err := os.MkdirAll(path.Dir(p), 0755); err != nil {
+		return err
+	}
+	tmp := p + ".tmp"
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p)
+}
+
+// Info reads mirrored map info
+func (m *Mirror) Info(hash string) (*MapInfo, error) {
+	b, err := os.ReadFile(m.apiPath("maps", path.Base(hash), "info.json"))
+	if err != nil {
+		return nil, err
+	}
+	info := &MapInfo{}
+	return info, json.Unmarshal(b, info)
+}
+
+// Maps reads mirrored index
+func (m *Mirror) Maps() ([]MapInfo, error) {
+	b, err := os.ReadFile(m.apiPath("full.json"))
+	if err != nil {
+		return nil, err
+	}
+	var page mapsPaginated
+	if err := json.Unmarshal(b, &page); err != nil {
+		return nil, err
+	}
+	return page.Maps, nil
+}
+
+// Handler serves mirror with the same paths as database and github releases,
+// point Client.APIURL to <server>/api/v1 and Client.ReleasesURL to <server>/releases.
+// Only api and releases trees are served, without directory listings and
+// files of unfinished writes.
+func (m *Mirror) Handler() http.Handler {
+	mux := http.NewServeMux()
+	for _, dir := range []string{"api", "releases"} {
+		fsys := mirrorFS{http.Dir(path.Join(m.Dir, dir))}
+		mux.Handle("/"+dir+"/", http.StripPrefix("/"+dir, http.FileServer(fsys)))
+	}
+	return mux
+}
+
+// mirrorFS hides directories and temporary files
+type mirrorFS struct {
+	http.FileSystem
+}
+
+func (fsys mirrorFS) Open(name string) (http.File, error) {
+	if strings.HasSuffix(name, ".tmp") {
+		return nil, fs.ErrNotExist
+	}
+	f, err := fsys.FileSystem.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if st.IsDir() {
+		f.Close()
+		return nil, fs.ErrNotExist
+	}
+	return f, nil
+}
@@ -0,0 +1,191 @@
+package mapsdatabase
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMirrorBlobPath(t *testing.T) {
+	m := &Mirror{Dir: "/srv/mirror"}
+	tests := []struct {
+		repo, path string
+		want       string
+		err        error
+	}{
+		{"2p", "v1/2c-Startup.wz", "/srv/mirror/releases/maps-2p/releases/download/v1/2c-Startup.wz", nil},
+		{"2p", "../../../../etc/passwd", "/srv/mirror/releases/maps-2p/releases/download/etc/passwd", nil},
+		{"../../etc", "x.wz", "", ErrUnsafePath},
+		{"..", "x.wz", "", ErrUnsafePath},
+		{"a/b", "x.wz", "", ErrUnsafePath},
+		{`a\b`, "x.wz", "", ErrUnsafePath},
+		{"", "x.wz", "", ErrUnsafePath},
+		{"2p", "", "", ErrUnsafePath},
+		{"2p", `..\..\x.wz`, "", ErrUnsafePath},
+	}
+	for _, tt := range tests {
+		info := &MapInfo{}
+		info.Download.Repo, info.Download.Path = tt.repo, tt.path
+		got, err := m.blobPath(info)
+		if got != tt.want || !errors.Is(err, tt.err) {
+			t.Errorf("repo %q path %q: got %q %v, want %q %v", tt.repo, tt.path, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+// testUpstream serves index of listed maps and their files, blobs of broken
+// maps fail
+type testUpstream struct {
+	mu     sync.Mutex
+	listed []string
+	paths  map[string]string
+	broken map[string]bool
+}
+
+func blobHash(name string) string {
+	h := sha256.Sum256([]byte(name))
+	return hex.EncodeToString(h[:])
+}
+
+func (u *testUpstream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if r.URL.Path == "/api/v1/full.json" {
+		maps := []string{}
+		for _, name := range u.listed {
+			maps = append(maps, fmt.Sprintf(`{"name": %q, "download": {"repo": "2p", "path": %q, "hash": %q}}`, name, u.paths[name], blobHash(name)))
+		}
+		fmt.Fprintf(w, `{"links": {}, "maps": [%s]}`, strings.Join(maps, ","))
+		return
+	}
+	for _, name := range u.listed {
+		switch r.URL.Path {
+		case "/api/v1/maps/" + blobHash(name) + "/preview.png", "/api/v1/maps/" + blobHash(name) + "/terrain.png":
+			w.Write([]byte("png"))
+			return
+		case "/releases/maps-2p/releases/download/" + u.paths[name]:
+			if u.broken[name] {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.Write([]byte(name))
+			return
+		}
+	}
+	http.NotFound(w, r)
+}
+
+func TestMirrorSyncPrune(t *testing.T) {
+	u := &testUpstream{
+		listed: []string{"A", "B", "C"},
+		paths:  map[string]string{"A": "v1/A.wz", "B": "v1/B.wz", "C": "v1/C.wz"},
+		broken: map[string]bool{},
+	}
+	m := NewMirror(t.TempDir())
+	m.Client = testClient(t, u)
+	m.Client.Retries = 0
+	m.Prune = true
+	ctx := context.Background()
+	res, err := m.Sync(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Total != 3 || res.Updated != 3 || len(res.Failed) != 0 {
+		t.Fatalf("first sync %+v", res)
+	}
+
+	// B is updated upstream but its new blob fails, C is dropped
+	u.mu.Lock()
+	u.listed = []string{"A", "B"}
+	u.paths["B"] = "v2/B.wz"
+	u.broken["B"] = true
+	u.mu.Unlock()
+	res, err = m.Sync(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Skipped != 1 || len(res.Failed) != 1 || res.Failed[blobHash("B")] == nil || res.Pruned != 1 {
+		t.Errorf("second sync %+v", res)
+	}
+	maps, err := m.Maps()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(maps) != 2 || maps[0].Name != "A" || maps[1].Name != "B" || maps[1].Download.Path != "v1/B.wz" {
+		t.Errorf("index %+v", maps)
+	}
+	if _, err := m.Info(blobHash("B")); err != nil {
+		t.Errorf("failed map removed: %v", err)
+	}
+	if _, err := os.Stat(path.Join(m.Dir, "releases/maps-2p/releases/download/v1/B.wz")); err != nil {
+		t.Errorf("failed map blob removed: %v", err)
+	}
+	if _, err := m.Info(blobHash("C")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("dropped map kept: %v", err)
+	}
+}
+
+func TestMirrorHandler(t *testing.T) {
+	m := NewMirror(t.TempDir())
+	for p, b := range map[string]string{
+		"api/v1/full.json":                           `{"maps": []}`,
+		"api/v1/full.json.tmp":                       "partial",
+		"releases/maps-2p/releases/download/v1/A.wz": "A",
+		"sync/state.json":                            "{}",
+	} {
+		if err := writeFileAtomic(path.Join(m.Dir, p), []byte(b)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	srv := httptest.NewServer(m.Handler())
+	defer srv.Close()
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{"/api/v1/full.json", http.StatusOK},
+		{"/releases/maps-2p/releases/download/v1/A.wz", http.StatusOK},
+		{"/api/v1/full.json.tmp", http.StatusNotFound},
+		{"/api/v1/", http.StatusNotFound},
+		{"/releases/maps-2p/", http.StatusNotFound},
+		{"/sync/state.json", http.StatusNotFound},
+		{"/api/../sync/state.json", http.StatusNotFound},
+		{"/", http.StatusNotFound},
+	}
+	cl := &http.Client{Timeout: time.Second}
+	for _, tt := range tests {
+		resp, err := cl.Get(srv.URL + tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s: status %d, want %d", tt.path, resp.StatusCode, tt.status)
+		}
+	}
+}
+
+func TestMirrorMaps(t *testing.T) {
+	m := NewMirror(t.TempDir())
+	if _, err := m.Maps(); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("missing index: got %v", err)
+	}
+	writeFileAtomic(m.apiPath("full.json"), []byte(`{"maps": [{"name": "A"}, {"name": 1}]}`))
+	if maps, err := m.Maps(); err == nil || maps != nil {
+		t.Errorf("malformed index: got %v %v", maps, err)
+	}
+	writeFileAtomic(m.apiPath("full.json"), []byte(`{"maps": [{"name": "A"}]}`))
+	if maps, err := m.Maps(); err != nil || len(maps) != 1 || maps[0].Name != "A" {
+		t.Errorf("index: got %v %v", maps, err)
+	}
+}
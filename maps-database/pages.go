@@ -0,0 +1,124 @@
+package mapsdatabase
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// PageError is returned when page of index fails to load, Link is page to
+// resume from with Client.Pages or Client.FetchMapsFrom
+type PageError struct {
+	Link string
+	Err  error
+}
+
+func (e *PageError) Error() string {
+	return "fetching maps page: " + e.Err.Error()
+}
+
+func (e *PageError) Unwrap() error {
+	return e.Err
+}
+
+// FetchAllMaps fetches whole index, see FetchMapsFrom
+func (c *Client) FetchAllMaps(ctx context.Context) ([]MapInfo, error) {
+	return c.FetchMapsFrom(ctx, "")
+}
+
+// FetchMapsFrom fetches index starting from page link. When page fails maps
+// of pages fetched so far are returned with *PageError to resume from.
+func (c *Client) FetchMapsFrom(ctx context.Context, link string) ([]MapInfo, error) {
+	ret := []MapInfo{}
+	it := c.Pages(ctx, link)
+	for it.Next() {
+		ret = append(ret, it.Maps()...)
+	}
+	if err := it.Err(); err != nil {
+		return ret, &PageError{Link: it.Link(), Err: err}
+	}
+	return ret, nil
+}
+
+// PageIterator walks paginated map index page by page:
+//
+//	it := c.Pages(ctx, "")
+//	for it.Next() {
+//		process(it.Maps())
+//		checkpoint(it.NextLink())
+//	}
+//	if err := it.Err(); err != nil {
+//		// resume later with c.Pages(ctx, it.Link())
+//	}
+type PageIterator struct {
+	c    *Client
+	ctx  context.Context
+	link string
+	next string
+	maps []MapInfo
+	raw  []json.RawMessage
+	err  error
+}
+
+// Pages returns iterator starting from link, empty link starts from first page of full.json
+func (c *Client) Pages(ctx context.Context, link string) *PageIterator {
+	if link == "" {
+		link = c.apiURL("full.json")
+	}
+	return &PageIterator{c: c, ctx: ctx, next: link}
+}
+
+// Next fetches next page, it returns false when there are no more pages or on error
+func (it *PageIterator) Next() bool {
+	if it.err != nil || it.next == "" {
+		return false
+	}
+	if it.err = it.ctx.Err(); it.err != nil {
+		return false
+	}
+	var page mapsPaginatedRaw
+	if it.err = it.c.getJSON(it.ctx, it.next, &page); it.err != nil {
+		return false
+	}
+	maps := make([]MapInfo, len(page.Maps))
+	for i, r := range page.Maps {
+		if it.err = json.Unmarshal(r, &maps[i]); it.err != nil {
+			return false
+		}
+	}
+	next := ""
+	if page.Links.Next != "" {
+		if next, it.err = it.c.resolveLink(page.Links.Next); it.err != nil {
+			return false
+		}
+	}
+	it.link, it.next = it.next, next
+	it.maps, it.raw = maps, page.Maps
+	return true
+}
+
+// Maps returns maps of current page
+func (it *PageIterator) Maps() []MapInfo {
+	return it.maps
+}
+
+// RawMaps returns maps of current page as they were received
+func (it *PageIterator) RawMaps() []json.RawMessage {
+	return it.raw
+}
+
+// Link returns link of current page, after error it is link of page that failed to load
+func (it *PageIterator) Link() string {
+	if it.err != nil {
+		return it.next
+	}
+	return it.link
+}
+
+// NextLink returns link to resume from after current page, empty after last page
+func (it *PageIterator) NextLink() string {
+	return it.next
+}
+
+func (it *PageIterator) Err() error {
+	return it.err
+}
@@ -0,0 +1,173 @@
+package mapsdatabase
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+var ErrUnknownField = errors.New("unknown field")
+
+const (
+	SortName    = "name"
+	SortSlots   = "slots"
+	SortSize    = "size"
+	SortOil     = "oil"
+	SortCreated = "created"
+)
+
+// Query filters map list, zero values of fields match everything
+type Query struct {
+	// Slots matches any of listed player counts
+	Slots   []int
+	Tileset string
+	MinW    int
+	MaxW    int
+	MinH    int
+	MaxH    int
+	MinOil  int
+	MaxOil  int
+	// Author and Text are case insensitive substrings, every word of Text has to be in name
+	Author  string
+	Text    string
+	License string
+	// Scavs requires maps with (true) or without (false) scavengers
+	Scavs *bool
+	// Balanced lists player fields that have to be equal for all players,
+	// for example units, structs or resourceExtr
+	Balanced []string
+	// Sort is one of Sort* constants, prefix with - for descending order
+	Sort  string
+	Limit int
+}
+
+func (m *MapInfo) balanceEq(field string) (bool, error) {
+	p := &m.Player
+	switch strings.ToLower(field) {
+	case "units":
+		return p.Units.Eq, nil
+	case "structs":
+		return p.Structs.Eq, nil
+	case "resourceextr":
+		return p.ResourceExtr.Eq, nil
+	case "pwrgen":
+		return p.PwrGen.Eq, nil
+	case "regfact":
+		return p.RegFact.Eq, nil
+	case "vtolfact":
+		return p.VtolFact.Eq, nil
+	case "cyborgfact":
+		return p.CyborgFact.Eq, nil
+	case "researchcent":
+		return p.ResearchCent.Eq, nil
+	case "defstruct":
+		return p.DefStruct.Eq, nil
+	}
+	return false, ErrUnknownField
+}
+
+// Validate checks balance fields and sort order
+func (q *Query) Validate() error {
+	var m MapInfo
+	for _, f := range q.Balanced {
+		if _, err := m.balanceEq(f); err != nil {
+			return err
+		}
+	}
+	switch strings.TrimPrefix(q.Sort, "-") {
+	case "", SortName, SortSlots, SortSize, SortOil, SortCreated:
+		return nil
+	}
+	return ErrUnknownField
+}
+
+func (q *Query) Match(m *MapInfo) bool {
+	if len(q.Slots) > 0 {
+		found := false
+		for _, s := range q.Slots {
+			if s == m.Slots {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if q.Tileset != "" && !strings.EqualFold(q.Tileset, m.Tileset) {
+		return false
+	}
+	if !inRange(m.Size.W, q.MinW, q.MaxW) || !inRange(m.Size.H, q.MinH, q.MaxH) || !inRange(m.OilWells, q.MinOil, q.MaxOil) {
+		return false
+	}
+	if q.License != "" && !strings.EqualFold(q.License, m.License) {
+		return false
+	}
+	if q.Scavs != nil && *q.Scavs != (m.Scavs > 0) {
+		return false
+	}
+	if q.Author != "" {
+		found := false
+		for _, a := range m.Authors {
+			if strings.Contains(strings.ToLower(a), strings.ToLower(q.Author)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	name := strings.ToLower(m.Name)
+	for _, w := range strings.Fields(strings.ToLower(q.Text)) {
+		if !strings.Contains(name, w) {
+			return false
+		}
+	}
+	for _, f := range q.Balanced {
+		if eq, err := m.balanceEq(f); err != nil || !eq {
+			return false
+		}
+	}
+	return true
+}
+
+func inRange(v, lo, hi int) bool {
+	return v >= lo && (hi == 0 || v <= hi)
+}
+
+// Search returns maps matching query in requested order, maps slice is not modified
+func Search(maps []MapInfo, q Query) []MapInfo {
+	ret := []MapInfo{}
+	for i := range maps {
+		if q.Match(&maps[i]) {
+			ret = append(ret, maps[i])
+		}
+	}
+	desc := strings.HasPrefix(q.Sort, "-")
+	var less func(a, b *MapInfo) bool
+	switch strings.TrimPrefix(q.Sort, "-") {
+	case SortName:
+		less = func(a, b *MapInfo) bool { return strings.ToLower(a.Name) < strings.ToLower(b.Name) }
+	case SortSlots:
+		less = func(a, b *MapInfo) bool { return a.Slots < b.Slots }
+	case SortSize:
+		less = func(a, b *MapInfo) bool { return a.Size.W*a.Size.H < b.Size.W*b.Size.H }
+	case SortOil:
+		less = func(a, b *MapInfo) bool { return a.OilWells < b.OilWells }
+	case SortCreated:
+		less = func(a, b *MapInfo) bool { return a.Created < b.Created }
+	}
+	if less != nil {
+		sort.SliceStable(ret, func(i, j int) bool {
+			if desc {
+				return less(&ret[j], &ret[i])
+			}
+			return less(&ret[i], &ret[j])
+		})
+	}
+	if q.Limit > 0 && len(ret) > q.Limit {
+		ret = ret[:q.Limit]
+	}
+	return ret
+}
@@ -0,0 +1,80 @@
+package mapsdatabase
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const testIndex = `[
+	{"name": "Sk-Rush", "slots": 4, "tileset": "arizona", "author": "Pumpkin Studios", "license": "GPL-2.0", "created": "2005-01-01",
+		"size": {"w": 64, "h": 128}, "scavs": 0, "oilWells": 40, "player": {"units": {"eq": true}, "structs": {"eq": true}}},
+	{"name": "Startup", "slots": 2, "tileset": "urban", "author": ["NoQ", "Berserk Cyborg"], "license": "CC0-1.0", "created": "2019-05-02",
+		"size": {"w": 60, "h": 60}, "scavs": 12, "oilWells": 16, "player": {"units": {"eq": true}}},
+	{"name": "Rush Hour", "slots": 8, "tileset": "rockies", "author": "pumpkin", "created": "2012-03-04",
+		"size": {"w": 250, "h": 250}, "scavs": 3, "oilWells": 120, "player": {"structs": {"eq": true}}}
+]`
+
+func TestSearch(t *testing.T) {
+	var maps []MapInfo
+	if err := json.Unmarshal([]byte(testIndex), &maps); err != nil {
+		t.Fatal(err)
+	}
+	yes, no := true, false
+	tests := []struct {
+		name string
+		q    Query
+		want []string
+	}{
+		{"everything", Query{}, []string{"Sk-Rush", "Startup", "Rush Hour"}},
+		{"slots", Query{Slots: []int{2, 8}}, []string{"Startup", "Rush Hour"}},
+		{"tileset", Query{Tileset: "URBAN"}, []string{"Startup"}},
+		{"width", Query{MinW: 61, MaxW: 100}, []string{"Sk-Rush"}},
+		{"height", Query{MinH: 100}, []string{"Sk-Rush", "Rush Hour"}},
+		{"oil", Query{MaxOil: 40}, []string{"Sk-Rush", "Startup"}},
+		{"author", Query{Author: "PUMPKIN"}, []string{"Sk-Rush", "Rush Hour"}},
+		{"second author", Query{Author: "cyborg"}, []string{"Startup"}},
+		{"license", Query{License: "gpl-2.0"}, []string{"Sk-Rush"}},
+		{"text words", Query{Text: "hour rush"}, []string{"Rush Hour"}},
+		{"scavs", Query{Scavs: &yes}, []string{"Startup", "Rush Hour"}},
+		{"no scavs", Query{Scavs: &no}, []string{"Sk-Rush"}},
+		{"balanced", Query{Balanced: []string{"units", "STRUCTS"}}, []string{"Sk-Rush"}},
+		{"sort name", Query{Sort: SortName}, []string{"Rush Hour", "Sk-Rush", "Startup"}},
+		{"sort slots descending", Query{Sort: "-" + SortSlots}, []string{"Rush Hour", "Sk-Rush", "Startup"}},
+		{"sort size", Query{Sort: SortSize}, []string{"Startup", "Sk-Rush", "Rush Hour"}},
+		{"sort oil", Query{Sort: SortOil}, []string{"Startup", "Sk-Rush", "Rush Hour"}},
+		{"sort created limited", Query{Sort: "-" + SortCreated, Limit: 2}, []string{"Startup", "Rush Hour"}},
+		{"nothing", Query{Slots: []int{3}}, []string{}},
+	}
+	for _, tt := range tests {
+		names := []string{}
+		for _, m := range Search(maps, tt.q) {
+			names = append(names, m.Name)
+		}
+		if !reflect.DeepEqual(names, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, names, tt.want)
+		}
+	}
+	if maps[0].Name != "Sk-Rush" || maps[2].Name != "Rush Hour" {
+		t.Error("search reordered input")
+	}
+}
+
+func TestQueryValidate(t *testing.T) {
+	tests := []struct {
+		q   Query
+		err error
+	}{
+		{Query{}, nil},
+		{Query{Sort: "-" + SortOil, Balanced: []string{"resourceExtr", "defStruct"}}, nil},
+		{Query{Sort: "hash"}, ErrUnknownField},
+		{Query{Sort: "--name"}, ErrUnknownField},
+		{Query{Balanced: []string{"units", "tanks"}}, ErrUnknownField},
+	}
+	for _, tt := range tests {
+		if err := tt.q.Validate(); !errors.Is(err, tt.err) {
+			t.Errorf("%+v: got %v, want %v", tt.q, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+
+	mapsdatabase "github.com/maxsupermanhd/go-wz/maps-database"
+)
+
+var (
+	dir     = flag.String("dir", "./mirror", "Path to mirror directory")
+	addr    = flag.String("addr", "127.0.0.1:8080", "Address to serve mirror on")
+	workers = flag.Int("workers", 4, "Number of maps downloaded at once")
+	prune   = flag.Bool("prune", false, "Remove maps that are no longer in database")
+	apiURL  = flag.String("api", mapsdatabase.DefaultAPIURL, "Maps database api url to sync from")
+)
+
+func main() {
+	log.SetFlags(0)
+	flag.Usage = func() {
+		log.Printf("Usage: %s [flags] sync|serve|search [search flags]", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	m := mapsdatabase.NewMirror(*dir)
+	m.Workers = *workers
+	m.Prune = *prune
+	m.Client.APIURL = *apiURL
+	m.Log = log.Printf
+	switch flag.Arg(0) {
+	case "sync":
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer cancel()
+		res, err := m.Sync(ctx)
+		if res != nil {
+			log.Printf("Total %d, updated %d, up to date %d, pruned %d, failed %d", res.Total, res.Updated, res.Skipped, res.Pruned, len(res.Failed))
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(res.Failed) > 0 {
+			os.Exit(1)
+		}
+	case "serve":
+		log.Printf("Serving %q on http://%s/api/v1/", *dir, *addr)
+		log.Fatal(http.ListenAndServe(*addr, m.Handler()))
+	case "search":
+		search(m, flag.Args()[1:])
+	default:
+		flag.Usage()
+		os.Exit(1)
+	}
+}
+
+func search(m *mapsdatabase.Mirror, args []string) {
+	fs := flag.NewFlagSet("search", flag.ExitOnError)
+	var (
+		q        mapsdatabase.Query
+		slots    = fs.String("slots", "", "Comma separated player counts")
+		scavs    = fs.String("scavs", "", "yes or no to require maps with or without scavengers")
+		balanced = fs.String("balanced", "", "Comma separated player fields that must be equal (units,structs,resourceExtr,pwrGen,regFact,vtolFact,cyborgFact,researchCent,defStruct)")
+		online   = fs.Bool("online", false, "Search database index instead of mirror")
+		asJSON   = fs.Bool("json", false, "Print matching maps as json")
+	)
+	fs.StringVar(&q.Tileset, "tileset", "", "Tileset (arizona, urban, rockies)")
+	fs.IntVar(&q.MinW, "minW", 0, "Minimum width")
+	fs.IntVar(&q.MaxW, "maxW", 0, "Maximum width")
+	fs.IntVar(&q.MinH, "minH", 0, "Minimum height")
+	fs.IntVar(&q.MaxH, "maxH", 0, "Maximum height")
+	fs.IntVar(&q.MinOil, "minOil", 0, "Minimum oil wells")
+	fs.IntVar(&q.MaxOil, "maxOil", 0, "Maximum oil wells")
+	fs.StringVar(&q.Author, "author", "", "Author name substring")
+	fs.StringVar(&q.License, "license", "", "License")
+	fs.StringVar(&q.Text, "q", "", "Words to find in map name")
+	fs.StringVar(&q.Sort, "sort", "name", "Sort by name, slots, size, oil or created, prefix with - for descending")
+	fs.IntVar(&q.Limit, "limit", 0, "Maximum number of results, 0 for all")
+	fs.Parse(args)
+	for _, s := range splitList(*slots) {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			log.Fatalf("Bad slot count %q", s)
+		}
+		q.Slots = append(q.Slots, n)
+	}
+	switch *scavs {
+	case "":
+	case "yes", "no":
+		v := *scavs == "yes"
+		q.Scavs = &v
+	default:
+		log.Fatalf("Bad scavs value %q", *scavs)
+	}
+	q.Balanced = splitList(*balanced)
+	if err := q.Validate(); err != nil {
+		log.Fatal(err)
+	}
+	var maps []mapsdatabase.MapInfo
+	var err error
+	if *online {
+		maps, err = m.Client.FetchAllMaps(context.Background())
+	} else {
+		maps, err = m.Maps()
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	res := mapsdatabase.Search(maps, q)
+	if *asJSON {
+		b, err := json.MarshalIndent(res, "", "\t")
+		if err != nil {
+			log.Fatal(err)
+		}
+		os.Stdout.Write(b)
+		return
+	}
+	for _, i := range res {
+		fmt.Printf("%s\t%dp\t%dx%d\t%s\toil %d\t%s\t%s\n", i.Download.Hash, i.Slots, i.Size.W, i.Size.H, i.Tileset, i.OilWells, strings.Join(i.Authors, ", "), i.Name)
+	}
+	log.Printf("%d of %d maps match", len(res), len(maps))
+}
+
+func splitList(s string) []string {
+	ret := []string{}
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
@@ -2,6 +2,7 @@ package packet
 
 import (
 	"fmt"
+	"image"
 	"io"
 	"path"
 	"runtime"
@@ -206,6 +207,27 @@ func ParseGameDroidInfo(p pk, r io.Reader) NetPacket {
 	return ret
 }
 
+// tileUnits is size of map tile in world units
+const tileUnits = 128
+
+func worldToTile(v int32) int {
+	w := int(v)
+	if w < 0 {
+		return -((-w + tileUnits - 1) / tileUnits)
+	}
+	return w / tileUnits
+}
+
+// Tile returns tile of order location (CoordX, CoordY)
+func (p PkGameDroidInfo) Tile() image.Point {
+	return image.Pt(worldToTile(p.CoordX), worldToTile(p.CoordY))
+}
+
+// Tile2 returns tile of line build end (CoordX2, CoordY2)
+func (p PkGameDroidInfo) Tile2() image.Point {
+	return image.Pt(worldToTile(p.CoordX2), worldToTile(p.CoordY2))
+}
+
 type PkGamePlayerLeft struct {
 	pk
 	Player uint8
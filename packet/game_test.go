@@ -0,0 +1,28 @@
+package packet
+
+import (
+	"image"
+	"testing"
+)
+
+func TestDroidInfoTile(t *testing.T) {
+	tests := []struct {
+		x, y int32
+		want image.Point
+	}{
+		{0, 0, image.Pt(0, 0)},
+		{127, 128, image.Pt(0, 1)},
+		{64*128 + 64, 3*128 + 64, image.Pt(64, 3)},
+		// positions off map edge round down
+		{-1, -129, image.Pt(-1, -2)},
+	}
+	for _, tt := range tests {
+		p := PkGameDroidInfo{CoordX: tt.x, CoordY: tt.y, CoordX2: tt.x, CoordY2: tt.y}
+		if got := p.Tile(); got != tt.want {
+			t.Errorf("Tile(%d, %d) = %v, want %v", tt.x, tt.y, got, tt.want)
+		}
+		if got := p.Tile2(); got != tt.want {
+			t.Errorf("Tile2(%d, %d) = %v, want %v", tt.x, tt.y, got, tt.want)
+		}
+	}
+}
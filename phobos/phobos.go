@@ -1,6 +1,7 @@
 package phobos
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"image"
@@ -8,6 +9,9 @@ import (
 	"image/png"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
 )
 
 var (
@@ -34,16 +38,70 @@ type MapInfo struct {
 	MapAsymmetrical bool   `json:"map_asymmetrical"`
 }
 
-func FetchOnePhobosInfo(hash string) (MapInfo, error) {
+const DefaultBaseURL = "https://wz2100.euphobos.ru/maps/"
+
+type Client struct {
+	// BaseURL is address of maps page, preview images are under preview/ of it
+	BaseURL    string
+	HTTPClient *http.Client
+	UserAgent  string
+}
+
+// NewClient returns client for phobos with 10 second timeout
+func NewClient() *Client {
+	return &Client{
+		BaseURL: DefaultBaseURL,
+		HTTPClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		UserAgent: "go-wz",
+	}
+}
+
+var defaultClient = NewClient()
+
+func (c *Client) get(ctx context.Context, p string, query url.Values) (*http.Response, error) {
+	base := c.BaseURL
+	if base == "" {
+		base = DefaultBaseURL
+	}
+	u := strings.TrimSuffix(base, "/") + "/" + p
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+	cl := c.HTTPClient
+	if cl == nil {
+		cl = http.DefaultClient
+	}
+	resp, err := cl.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, ErrBadPhobosAnswer
+	}
+	return resp, nil
+}
+
+func (c *Client) FetchInfo(ctx context.Context, hash string) (MapInfo, error) {
 	var ret MapInfo
 	ret.MapSha = hash
-	resp, err := http.Get("https://wz2100.euphobos.ru/maps/?api=json&s=" + hash)
+	resp, err := c.get(ctx, "", url.Values{"api": {"json"}, "s": {hash}})
 	if err != nil {
 		return ret, err
 	}
-	if resp.StatusCode != 200 {
-		return ret, ErrBadPhobosAnswer
-	}
+	defer resp.Body.Close()
 	b, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return ret, err
@@ -62,6 +120,24 @@ func FetchOnePhobosInfo(hash string) (MapInfo, error) {
 	return ret, ErrNotFound
 }
 
+func (c *Client) FetchPreview(ctx context.Context, hash string, t PreviewType) (image.Image, error) {
+	resp, err := c.get(ctx, "preview/"+hash+string(t), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch t {
+	case PreviewTypeLargeJPEG:
+		return jpeg.Decode(resp.Body)
+	default:
+		return png.Decode(resp.Body)
+	}
+}
+
+func FetchOnePhobosInfo(hash string) (MapInfo, error) {
+	return defaultClient.FetchInfo(context.Background(), hash)
+}
+
 type PreviewType string
 
 const (
@@ -71,25 +147,5 @@ const (
 )
 
 func FetchMapPreview(hash string, t PreviewType) (image.Image, error) {
-	url := "https://wz2100.euphobos.ru/maps/preview/" + hash + string(t)
-	resp, err := http.Get(url)
-	if err != nil {
-		return nil, err
-	}
-	if resp.StatusCode != 200 {
-		return nil, ErrBadPhobosAnswer
-	}
-	var i image.Image
-	switch t {
-	case PreviewTypeHeightmap:
-		fallthrough
-	case PreviewTypePixelPerfect:
-		i, err = png.Decode(resp.Body)
-	case PreviewTypeLargeJPEG:
-		i, err = jpeg.Decode(resp.Body)
-	}
-	if err != nil {
-		return nil, err
-	}
-	return i, err
+	return defaultClient.FetchPreview(context.Background(), hash, t)
 }
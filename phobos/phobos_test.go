@@ -0,0 +1,115 @@
+package phobos
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const testHash = "f1c7d8e0a4b5c6d7e8f90a1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f"
+
+func testClient(t *testing.T, h http.HandlerFunc) *Client {
+	t.Helper()
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	c := NewClient()
+	c.BaseURL = srv.URL + "/maps/"
+	c.UserAgent = "go-wz-test"
+	return c
+}
+
+func TestFetchInfo(t *testing.T) {
+	c := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if r.URL.Path != "/maps/" || q.Get("api") != "json" || r.UserAgent() != "go-wz-test" {
+			t.Errorf("request %s user agent %q", r.URL, r.UserAgent())
+		}
+		switch q.Get("s") {
+		case testHash:
+			w.Write([]byte(`{"1": {"map_name": "Sk-Rush", "map_sha": "` + testHash + `", "map_players": 4, "map_oilwells": 40, "map_scr_x2": 64}}`))
+		case "multiple":
+			w.Write([]byte(`{"1": {"map_name": "A"}, "2": {"map_name": "B"}}`))
+		case "empty":
+			w.Write([]byte(`{}`))
+		case "malformed":
+			w.Write([]byte(`<html>maintenance</html>`))
+		case "gone":
+			http.NotFound(w, r)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+	ctx := context.Background()
+	info, err := c.FetchInfo(ctx, testHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.MapName != "Sk-Rush" || info.MapSha != testHash || info.MapPlayers != 4 || info.MapOilwells != 40 || info.MapScrX2 != 64 {
+		t.Errorf("info %+v", info)
+	}
+	tests := []struct {
+		hash string
+		err  error
+	}{
+		{"multiple", ErrMultipleByHash},
+		{"empty", ErrNotFound},
+		{"gone", ErrNotFound},
+		{"broken", ErrBadPhobosAnswer},
+	}
+	for _, tt := range tests {
+		if _, err := c.FetchInfo(ctx, tt.hash); !errors.Is(err, tt.err) {
+			t.Errorf("%s: got %v, want %v", tt.hash, err, tt.err)
+		}
+	}
+	if _, err := c.FetchInfo(ctx, "malformed"); err == nil {
+		t.Error("malformed body decoded")
+	}
+}
+
+func TestFetchPreview(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	buf := &bytes.Buffer{}
+	if err := png.Encode(buf, img); err != nil {
+		t.Fatal(err)
+	}
+	c := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/maps/preview/" + testHash + ".png":
+			w.Write(buf.Bytes())
+		case "/maps/preview/" + testHash + "_h.png":
+			w.Write([]byte("not an image"))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	ctx := context.Background()
+	got, err := c.FetchPreview(ctx, testHash, PreviewTypePixelPerfect)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Bounds() != img.Bounds() {
+		t.Errorf("preview bounds %v", got.Bounds())
+	}
+	if _, err := c.FetchPreview(ctx, testHash, PreviewTypeHeightmap); err == nil {
+		t.Error("malformed preview decoded")
+	}
+	if _, err := c.FetchPreview(ctx, testHash, PreviewTypeLargeJPEG); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing preview: got %v", err)
+	}
+}
+
+func TestFetchInfoCanceled(t *testing.T) {
+	c := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.FetchInfo(ctx, testHash); !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want context canceled", err)
+	}
+}
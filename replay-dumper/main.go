@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
@@ -16,13 +17,14 @@ import (
 	"strings"
 	"time"
 
-	"golang.org/x/image/draw"
-
 	"github.com/davecgh/go-spew/spew"
-	"github.com/dustin/go-heatmap"
-	"github.com/dustin/go-heatmap/schemes"
 	"github.com/dustin/go-humanize"
+	"github.com/maxsupermanhd/go-wz/heatmap"
+	"github.com/maxsupermanhd/go-wz/mapcache"
+	"github.com/maxsupermanhd/go-wz/mapinfo"
+	mapsdatabase "github.com/maxsupermanhd/go-wz/maps-database"
 	"github.com/maxsupermanhd/go-wz/phobos"
+	"github.com/maxsupermanhd/go-wz/wzmap"
 	"github.com/maxsupermanhd/go-wz/wznet"
 )
 
@@ -30,6 +32,7 @@ var (
 	filepath               = flag.String("f", "./replay.wzrp", "Path to replay to dump, can be a url if fetch is true")
 	fetch                  = flag.Bool("fetch", false, "If true treat filepath as url and fetch replay into memory from it")
 	statsdir               = flag.String("stats", "./data/mp/stats/", "Path to stats directory")
+	statsMods              = flag.String("statsMods", "", "Comma separated paths to mod stats directories merged over stats")
 	mapout                 = flag.String("mapout", "./map.wz", "Path to save embedded map. Use - to disable")
 	short                  = flag.Bool("short", false, "Do not print out everything")
 	dOrder                 = flag.Bool("dorder", false, "Dump unit commands")
@@ -41,19 +44,26 @@ var (
 	genHeatmapPath         = flag.String("heatmapOut", "./heatmap.png", "Path out for heatmap")
 	heatmapIntensity       = flag.Int("heatmapIntensity", 20, "The impact size of each point on the output")
 	heatmapScale           = flag.Int("mapZ", 32, "Scale of heatmap")
-	mapW                   = flag.Int("mapW", 2048, "Map width")
-	mapH                   = flag.Int("mapH", 2048, "Map height")
-	phobosInfo             = flag.Bool("phobosInfo", true, "Fetch information about map from wz2100.euphobos.net/maps/")
+	mapW                   = flag.Int("mapW", 2048, "Map width, used when replay has no embedded map")
+	mapH                   = flag.Int("mapH", 2048, "Map height, used when replay has no embedded map")
+	phobosInfo             = flag.Bool("phobosInfo", true, "Fetch information about map from online sources of mapInfoSources, only embedded map is used if false")
+	mapInfoSources         = flag.String("mapInfoSources", "embedded,mapsdatabase,phobos", "Comma separated order of map info sources (embedded, mapsdatabase, phobos)")
 	phobosMapSizeFromScr   = flag.Bool("phobosMapSizeFromScr", false, "Get map size from map_scr or map_size")
 	phobosPreview          = flag.Bool("phobosPreview", true, "Fetch map preview from wz2100.euphobos.net/maps/")
 	phobosPreviewHeightmap = flag.Bool("phobosPreviewHeightmap", false, "Fetch map preview from wz2100.euphobos.net/maps/ in heightmap format")
 	filePreview            = flag.String("filePreview", "", "Overlay map preview (png) from this path")
+	embeddedPreview        = flag.Bool("embeddedPreview", true, "Render map preview from map embedded in replay (preferred over phobos)")
+	previewOut             = flag.String("previewOut", "", "Path to save map preview rendered from embedded map")
+	terrainOut             = flag.String("terrainOut", "", "Path to save terrain image rendered from embedded map")
 	overrideMapHash        = flag.String("overrideHash", "", "Optional override for map hash")
+	cacheDir               = flag.String("cache", "", "Directory to cache map info and previews in, empty to disable")
 	netPlayPlayers         = []NetplayPlayers{}
 	namePadLength          = 2
 	// clickHeatmap     = map[int]clickPoint{}
-	clickHeatmap  = []heatmap.DataPoint{}
+	clickHeatmap  = []image.Point{}
 	mapHash       = ""
+	mapCache      *mapcache.Cache
+	embeddedMap   *wzmap.Map
 	replayOptions = GameOptions{}
 )
 
@@ -68,9 +78,18 @@ func main() {
 	flag.Parse()
 	PrintNShort("Replay dumper starting up...")
 
-	if *dResearch || *dStructinfo || *checkIllegals {
+	// one cache is shared by all clients so its index is not overwritten
+	if *cacheDir != "" {
+		mapCache = noerr(mapcache.Open(*cacheDir, 24*time.Hour, 1<<30))
+	}
+
+	if *dResearch || *dStructinfo || *checkIllegals || *dOrder {
 		log.Printf("Loading stats from [%s]...", *statsdir)
-		must(loadStatsData(*statsdir))
+		mods := []string{}
+		if *statsMods != "" {
+			mods = strings.Split(*statsMods, ",")
+		}
+		must(loadStatsData(*statsdir, mods))
 	}
 
 	var f *bytes.Buffer
@@ -95,58 +114,43 @@ func main() {
 	readNetMessages(f)
 
 	if *genHeatmap {
-		mw := *mapW
-		mh := *mapH
-		if *phobosInfo {
-			log.Println("Fetching info about map...")
-			info := noerr(phobos.FetchOnePhobosInfo(mapHash))
+		area := image.Rect(0, 0, *mapW, *mapH)
+		if info, err := mapInfoResolver().Resolve(context.Background(), mapHash, embeddedMap); err != nil {
+			log.Printf("Failed to resolve map info (%v), using map size from flags", err)
+		} else {
+			area = image.Rect(0, 0, info.Width, info.Height)
 			if *phobosMapSizeFromScr {
-				mw = info.MapScrX2
-				mh = info.MapScrY2
-			} else {
-				fmt.Sscanf(info.MapSize, "%dx%d", &mw, &mh)
-			}
-			log.Printf("Size: W %d H %d", mw, mh)
-		}
-		log.Println("Generating heatmap...")
-		clickHeatmap = append(clickHeatmap, heatmap.P(0, 0))
-		clickHeatmap = append(clickHeatmap, heatmap.P(float64(mw*128), float64(mh*128)))
-		for i, v := range clickHeatmap {
-			clickHeatmap[i] = heatmap.P(v.X(), v.Y()*-1+float64(mh*128))
-			if int(clickHeatmap[i].X()) > mw*128 {
-				clickHeatmap[i] = heatmap.P(float64(mw*128), clickHeatmap[i].Y())
-			}
-			if int(clickHeatmap[i].Y()) > mh*128 {
-				clickHeatmap[i] = heatmap.P(clickHeatmap[i].X(), float64(mw*128))
+				area = image.Rect(0, 0, info.Scroll.Max.X, info.Scroll.Max.Y)
 			}
+			log.Printf("Map info from %s: %q by %v, %d players, size W %d H %d scroll %v", info.Source, info.Name, info.Authors, info.Players, info.Width, info.Height, info.Scroll)
 		}
+		o := heatmap.DefaultOptions
+		o.Scale = *heatmapScale
+		o.Intensity = *heatmapIntensity
 		log.Printf("Plotting %d samples...", len(clickHeatmap))
-		hm := heatmap.Heatmap(image.Rect(0, 0, *heatmapScale*mw, *heatmapScale*mh), clickHeatmap, *heatmapIntensity, 255, schemes.AlphaFire)
+		var hm image.Image
 		if *filePreview != "" {
 			b := noerr(os.ReadFile(*filePreview))
-			prv := noerr(png.Decode(bytes.NewBuffer(b)))
-			log.Println("Rendering heightmap with preview...")
-			i := image.NewRGBA(image.Rect(0, 0, *heatmapScale*mw, *heatmapScale*mh))
-			draw.NearestNeighbor.Scale(i, i.Rect, prv, prv.Bounds(), draw.Over, nil)
-			draw.Draw(i, i.Bounds(), hm, image.Point{}, draw.Over)
-			hm = i
+			o.Background = noerr(png.Decode(bytes.NewBuffer(b)))
+			log.Println("Rendering heatmap with preview...")
+			hm = heatmap.Render(area, clickHeatmap, o)
+		} else if *embeddedPreview && embeddedMap != nil {
+			log.Println("Rendering heatmap with embedded map preview...")
+			hm = heatmap.RenderMap(embeddedMap, clickHeatmap, o)
 		} else if *phobosPreview {
 			log.Println("Fetching map preview...")
 			ptf := phobos.PreviewTypePixelPerfect
 			if *phobosPreviewHeightmap {
 				ptf = phobos.PreviewTypeHeightmap
 			}
-			prv := noerr(phobos.FetchMapPreview(mapHash, ptf))
-			log.Println("Rendering heightmap with preview...")
-			i := image.NewRGBA(image.Rect(0, 0, *heatmapScale*mw, *heatmapScale*mh))
-			draw.NearestNeighbor.Scale(i, i.Rect, prv, prv.Bounds(), draw.Over, nil)
-			draw.Draw(i, i.Bounds(), hm, image.Point{}, draw.Over)
-			hm = i
+			o.Background = noerr(phobosClient().FetchPreview(context.Background(), mapHash, ptf))
+			log.Println("Rendering heatmap with preview...")
+			hm = heatmap.Render(area, clickHeatmap, o)
+		} else {
+			hm = heatmap.Render(area, clickHeatmap, o)
 		}
 		log.Printf("Encoding heatmap to %q...", *genHeatmapPath)
-		b := bytes.NewBuffer([]byte{})
-		must(png.Encode(b, hm))
-		must(os.WriteFile(*genHeatmapPath, b.Bytes(), 0644))
+		writePNG(*genHeatmapPath, hm)
 	}
 
 	PrintNShort("Bye!")
@@ -265,7 +269,7 @@ func readNetMessages(f *bytes.Buffer) {
 					if len(droidWeapons) > 2 {
 						rprint("Built droid with more than 2 turrets! %v", droid)
 					}
-					droidTyped := typifyDroid(droid)
+					droidTyped := typifyDroid(droid, droidWeapons)
 					if droidTyped != nil {
 						if *dumpUnits {
 							printparams = append(printparams, "droid", spew.Sdump(*droidTyped))
@@ -306,26 +310,39 @@ func readNetMessages(f *bytes.Buffer) {
 				case wznet.DroidOrderSybTypeLoc:
 					order := wznet.DORDER(noerr(wznet.NETreadU32(r)))
 					printparams = append(printparams, order.String())
+					var coordx, coordy int32
 					if subtype == wznet.DroidOrderSybTypeObj {
 						destID := noerr(wznet.NETreadU32(r))
 						destType := noerr(wznet.NETreadU32(r))
 						printparams = append(printparams, "destID", destID, "destType", destType)
+						if embeddedMap != nil {
+							if o := embeddedMap.ObjectByID(destID); o != nil {
+								printparams = append(printparams, "map object", o.Name, "tile", o.Tile())
+							}
+						}
 					} else {
-						coordx := noerr(wznet.NETreadS32(r))
-						coordy := noerr(wznet.NETreadS32(r))
+						coordx = noerr(wznet.NETreadS32(r))
+						coordy = noerr(wznet.NETreadS32(r))
 						if *genHeatmap {
 							// clickHeatmap[replayOptions.NetplayPlayers[player].Position] = clickPoint{
 							// 	Pos:  heatmap.P(float64(coordx), float64(coordy)),
 							// 	Tick: gameTime,
 							// }
-							clickHeatmap = append(clickHeatmap, heatmap.P(float64(coordx), float64(coordy)))
+							clickHeatmap = append(clickHeatmap, image.Pt(int(coordx), int(coordy)))
 						}
-						printparams = append(printparams, "x", coordx, "y", coordy, "tile aligned", coordx%128 == 0, coordy%128 == 0)
+						printparams = append(printparams, "x", coordx, "y", coordy, "tile", wzmap.WorldToTile(int(coordx), int(coordy)), "tile aligned", coordx%128 == 0, coordy%128 == 0)
 					}
 					if order == wznet.DORDER_BUILD || order == wznet.DORDER_LINEBUILD {
 						structref := noerr(wznet.NETreadU32(r))
 						direction := noerr(wznet.NETreadU16(r))
 						printparams = append(printparams, "structref", refToStructName(structref), "direction", direction)
+						if order == wznet.DORDER_BUILD && embeddedMap != nil && isDerrickRef(structref) {
+							if oil := embeddedMap.OilAt(int(coordx), int(coordy)); oil != nil {
+								printparams = append(printparams, "oil", oil.Tile())
+							} else {
+								printparams = append(printparams, "oil", "none")
+							}
+						}
 					}
 					if order == wznet.DORDER_LINEBUILD {
 						coordx2 := noerr(wznet.NETreadS32(r))
@@ -371,13 +388,7 @@ func readNetMessages(f *bytes.Buffer) {
 				building := noerr(wznet.NETreadU32(r))
 				_ = building
 				topic := noerr(wznet.NETreadU32(r))
-				topicname := fmt.Sprint(topic)
-				_ = topicname
-				if int(topic) < len(aResearch) {
-					topicname = aResearch[topic].Name
-				} else {
-					log.Printf("Topic overflow or underflow, topic %d total %d", topic, len(aResearch))
-				}
+				topicname := researchName(topic)
 				// if player != pPlayer {
 				// 	log.Printf("Player missmatch in %s (%d netmessage %d packet)", msgid, pPlayer, player)
 				// }
@@ -428,11 +439,63 @@ func readEmbeddedMap(f *bytes.Buffer) {
 		if *mapout != "-" {
 			must(os.WriteFile(*mapout, b, 0644))
 		}
+		// mapHash is already replaced by -overrideHash when it is set
+		if err := wzmap.VerifyHash(b, mapHash); err != nil {
+			from := "settings"
+			if *overrideMapHash != "" {
+				from = "-overrideHash"
+			}
+			log.Printf("Embedded map hash %s does not match map hash %s from %s", wzmap.Hash(b), mapHash, from)
+		}
+		m, err := wzmap.Open(b)
+		if err != nil {
+			log.Printf("Failed to parse embedded map: %v", err)
+			return
+		}
+		embeddedMap = m
+		PrintNShort("Embedded map %q %dx%d", m.Name, m.Width, m.Height)
+		if *previewOut != "" {
+			writePNG(*previewOut, m.Preview(wzmap.DefaultPreviewOptions))
+		}
+		if *terrainOut != "" {
+			writePNG(*terrainOut, m.Terrain())
+		}
 	} else {
 		PrintNShort("Embedded map data is empty")
 	}
 }
 
+// httpCache returns cache opened at startup, nil when caching is disabled
+func httpCache() *mapcache.Cache {
+	return mapCache
+}
+
+func phobosClient() *phobos.Client {
+	c := phobos.NewClient()
+	if cache := httpCache(); cache != nil {
+		c.HTTPClient = cache.HTTPClient(c.HTTPClient.Timeout)
+	}
+	return c
+}
+
+func mapInfoResolver() *mapinfo.Resolver {
+	sources := "embedded"
+	if *phobosInfo {
+		sources = *mapInfoSources
+	}
+	md := mapsdatabase.NewClient()
+	if cache := httpCache(); cache != nil {
+		md.HTTPClient = cache.HTTPClient(md.HTTPClient.Timeout)
+	}
+	return mapinfo.NewResolver(noerr(mapinfo.SourcesByName(sources, md, phobosClient()))...)
+}
+
+func writePNG(p string, i image.Image) {
+	b := bytes.NewBuffer([]byte{})
+	must(png.Encode(b, i))
+	must(os.WriteFile(p, b.Bytes(), 0644))
+}
+
 func readSettings(f *bytes.Buffer) {
 	b := noerr(wznet.ReadBytes(f, int(noerr(wznet.ReadUBE32(f)))))
 	var s ReplaySettings
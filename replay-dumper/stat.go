@@ -1,87 +1,25 @@
 package main
 
 import (
-	"encoding/json"
+	"fmt"
 	"log"
-	"os"
-	"path"
-	"sort"
+	"strings"
 
+	"github.com/maxsupermanhd/go-wz/stat"
 	"github.com/maxsupermanhd/go-wz/wznet"
 )
 
 var (
-	aResearch     = []IDNameObject{}
-	aBody         = []IDNameObject{}
-	aConstruction = []IDNameObject{}
-	aECM          = []IDNameObject{}
-	aPropulsion   = []IDNameObject{}
-	aRepair       = []IDNameObject{}
-	aSensor       = []IDNameObject{}
-	aStructure    = []IDNameObject{}
-	aWeapons      = []IDNameObject{}
+	// stats are loaded only when flags need them, helpers below handle nil
+	stats *stat.Stats
 )
 
-type IDNameObject struct {
-	ID   string `json:"id"`
-	Name string `json:"name"`
-}
-
-func loadDefaultData(p string, out *[]IDNameObject) (err error) {
-	b, err := os.ReadFile(p)
-	if err != nil {
-		return err
-	}
-	r := map[string]IDNameObject{}
-	err = json.Unmarshal(b, &r)
-	if err != nil {
-		return err
-	}
-	keys := make([]string, 0, len(r))
-	for k := range r {
-		keys = append(keys, k)
-	}
-	sort.Strings(keys)
-	ret := []IDNameObject{}
-	for _, k := range keys {
-		ret = append(ret, r[k])
-	}
-	*out = ret
-	return nil
-}
-
-func loadStatsData(p string) (err error) {
+func loadStatsData(p string, mods []string) (err error) {
 	if p == "" {
 		p = "./data/mp/stats/"
 	}
-	if err := loadDefaultData(path.Join(p, "body.json"), &aBody); err != nil {
-		return err
-	}
-	if err := loadDefaultData(path.Join(p, "construction.json"), &aConstruction); err != nil {
-		return err
-	}
-	if err := loadDefaultData(path.Join(p, "ecm.json"), &aECM); err != nil {
-		return err
-	}
-	if err := loadDefaultData(path.Join(p, "propulsion.json"), &aPropulsion); err != nil {
-		return err
-	}
-	if err := loadDefaultData(path.Join(p, "repair.json"), &aRepair); err != nil {
-		return err
-	}
-	if err := loadDefaultData(path.Join(p, "research.json"), &aResearch); err != nil {
-		return err
-	}
-	if err := loadDefaultData(path.Join(p, "sensor.json"), &aSensor); err != nil {
-		return err
-	}
-	if err := loadDefaultData(path.Join(p, "structure.json"), &aStructure); err != nil {
-		return err
-	}
-	if err := loadDefaultData(path.Join(p, "weapons.json"), &aWeapons); err != nil {
-		return err
-	}
-	return nil
+	stats, err = stat.LoadStatsWithMods(p, mods...)
+	return err
 }
 
 type DroidDef struct {
@@ -108,59 +46,90 @@ type DroidTyped struct {
 	Ecm        string
 	Sensor     string
 	Construct  string
+	Weapons    []string
 }
 
-func isOf(v string, c ...string) bool {
-	for _, t := range c {
-		if t == v {
-			return true
-		}
-	}
-	return false
+func checkDroidIllegal(d DroidTyped) string {
+	if stats == nil {
+		return ""
+	}
+	r := stats.CheckDesign(stat.Design{
+		Body:       d.Body,
+		Brain:      d.Brain,
+		Propulsion: d.Propulsion,
+		Repair:     d.Repairunit,
+		ECM:        d.Ecm,
+		Sensor:     d.Sensor,
+		Construct:  d.Construct,
+		Weapons:    d.Weapons,
+	}, nil)
+	return strings.Join(r.Problems, ", ")
 }
 
-func checkDroidIllegal(d DroidTyped) string {
-	if isOf(d.Propulsion, "BaBaLegs", "BaBaProp") {
-		return "Ba Ba prop"
+func componentID(t stat.COMPONENT_TYPE, index uint8) string {
+	if stats == nil {
+		return ""
 	}
-	if d.Propulsion == "CyborgLegs" && !isOf(d.Body, "CyborgHeavyBody", "CyborgLightBody") {
-		return "Cyborg with wrong body"
-	}
-	return ""
+	id, _ := stats.ComponentID(t, int(index))
+	return id
 }
 
-func typifyDroid(d DroidDef) *DroidTyped {
+func typifyDroid(d DroidDef, weapons []uint32) *DroidTyped {
 	ret := &DroidTyped{
-		Name: d.Name,
-		ID:   d.ID,
-		Type: d.Type,
-	}
-	if len(aBody) > int(d.Body) {
-		ret.Body = aBody[d.Body].ID
-	}
-	if len(aPropulsion) > int(d.Propulsion) {
-		ret.Propulsion = aPropulsion[d.Propulsion].ID
-	}
-	if len(aRepair) > int(d.Repairunit) {
-		ret.Repairunit = aRepair[d.Repairunit].ID
-	}
-	if len(aECM) > int(d.Ecm) {
-		ret.Ecm = aECM[d.Ecm].ID
-	}
-	if len(aSensor) > int(d.Sensor) {
-		ret.Sensor = aSensor[d.Sensor].ID
+		Name:       d.Name,
+		ID:         d.ID,
+		Type:       d.Type,
+		Body:       componentID(stat.COMP_BODY, d.Body),
+		Brain:      componentID(stat.COMP_BRAIN, d.Brain),
+		Propulsion: componentID(stat.COMP_PROPULSION, d.Propulsion),
+		Repairunit: componentID(stat.COMP_REPAIRUNIT, d.Repairunit),
+		Ecm:        componentID(stat.COMP_ECM, d.Ecm),
+		Sensor:     componentID(stat.COMP_SENSOR, d.Sensor),
+		Construct:  componentID(stat.COMP_CONSTRUCT, d.Construct),
+	}
+	if stats == nil {
+		return ret
+	}
+	for _, w := range weapons {
+		if id, ok := stats.ComponentID(stat.COMP_WEAPON, int(w)); ok {
+			ret.Weapons = append(ret.Weapons, id)
+		}
 	}
 	return ret
 }
 
 func refToStructName(ref uint32) string {
 	if ref&wznet.STAT_MASK == wznet.STAT_STRUCTURE {
+		if stats == nil {
+			return ""
+		}
 		structid := int(ref - wznet.STAT_STRUCTURE)
-		if len(aStructure) <= structid {
-			log.Printf("Structure ref lookup overflow %d, total %d", structid, len(aStructure))
+		id, ok := stats.StructureID(structid)
+		if !ok {
+			log.Printf("Structure ref lookup overflow %d, total %d", structid, len(stats.Order.Structure))
 			return "overflow"
 		}
-		return aStructure[structid].Name
+		return stats.Structure[id].Name
 	}
 	return "notastructure"
 }
+
+func isDerrickRef(ref uint32) bool {
+	if stats == nil || ref&wznet.STAT_MASK != wznet.STAT_STRUCTURE {
+		return false
+	}
+	id, ok := stats.StructureID(int(ref - wznet.STAT_STRUCTURE))
+	return ok && stats.Structure[id].Type == "RESOURCE EXTRACTOR"
+}
+
+func researchName(topic uint32) string {
+	if stats == nil {
+		return fmt.Sprint(topic)
+	}
+	id, ok := stats.ResearchID(int(topic))
+	if !ok {
+		log.Printf("Topic overflow or underflow, topic %d total %d", topic, len(stats.Order.Research))
+		return fmt.Sprint(topic)
+	}
+	return stats.Research[id].Name
+}
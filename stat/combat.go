@@ -0,0 +1,166 @@
+package stat
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+var (
+	ErrUnknownWeapon    = errors.New("unknown weapon")
+	ErrUnknownStructure = errors.New("unknown structure")
+	ErrIllegalDesign    = errors.New("design has no body or propulsion")
+	ErrOutOfRange       = errors.New("target is out of weapon range")
+)
+
+// weapon timings (firePause, reloadTime, periodicalDamageTime) are stored in tenths of a second
+const statTimeUnit = 100 * time.Millisecond
+
+type CombatResult struct {
+	// DamagePerShot is damage dealt by one hit after modifiers and armour
+	DamagePerShot int
+	// BurnDamage is periodical damage dealt per second after modifiers and armour
+	BurnDamage int
+	HitChance  int
+	HitPoints  int
+	// DPS is damage per second of hitting shots, ExpectedDPS accounts for hit chance
+	DPS         float64
+	ExpectedDPS float64
+	ShotsToKill int
+	TimeToKill  time.Duration
+}
+
+type combatTarget struct {
+	hitpoints     int
+	armourKinetic int
+	armourHeat    int
+	modifier      func(effect string) int
+}
+
+// WeaponVsDesign calculates damage of attacker weapon against droid of design at range (world units),
+// range 0 means long range. Upgrades may be nil to use base stats.
+func (s *Stats) WeaponVsDesign(weapon string, attacker *Upgrades, d Design, defender *Upgrades, rng int) (CombatResult, error) {
+	if defender == nil {
+		defender = s.NewUpgrades()
+	}
+	body := defender.Body(d.Body)
+	prop := defender.Propulsion(d.Propulsion)
+	if body == nil || prop == nil {
+		return CombatResult{}, ErrIllegalDesign
+	}
+	design := s.CheckDesign(d, defender)
+	return s.weaponVsTarget(weapon, attacker, combatTarget{
+		hitpoints:     design.HitPoints,
+		armourKinetic: body.ArmourKinetic,
+		armourHeat:    body.ArmourHeat,
+		modifier: func(effect string) int {
+			if m, ok := s.WeaponModifier[effect][prop.Type]; ok {
+				return m
+			}
+			return 100
+		},
+	}, rng)
+}
+
+// WeaponVsStructure calculates damage of attacker weapon against structure at range (world units),
+// range 0 means long range. Upgrades may be nil to use base stats.
+func (s *Stats) WeaponVsStructure(weapon string, attacker *Upgrades, structure string, defender *Upgrades, rng int) (CombatResult, error) {
+	if defender == nil {
+		defender = s.NewUpgrades()
+	}
+	st := defender.Structure(structure)
+	if st == nil {
+		return CombatResult{}, ErrUnknownStructure
+	}
+	return s.weaponVsTarget(weapon, attacker, combatTarget{
+		hitpoints:     st.HitPoints,
+		armourKinetic: st.Armour,
+		armourHeat:    st.Thermal,
+		modifier: func(effect string) int {
+			if m, ok := s.StructureModifier[effect][st.Strength]; ok {
+				return m
+			}
+			return 100
+		},
+	}, rng)
+}
+
+func (s *Stats) weaponVsTarget(weapon string, attacker *Upgrades, t combatTarget, rng int) (CombatResult, error) {
+	ret := CombatResult{HitPoints: t.hitpoints}
+	if attacker == nil {
+		attacker = s.NewUpgrades()
+	}
+	w := attacker.Weapon(weapon)
+	if w == nil {
+		return ret, ErrUnknownWeapon
+	}
+	if rng == 0 {
+		rng = w.LongRange
+	}
+	if rng > w.LongRange || rng < w.MinRange {
+		return ret, ErrOutOfRange
+	}
+	ret.HitChance = w.LongHit
+	if rng <= w.ShortRange {
+		ret.HitChance = w.ShortHit
+	}
+	ret.DamagePerShot = applyDamage(w.Damage, t.modifier(w.WeaponEffect), armourFor(t, w.WeaponClass), w.MinimumDamage)
+	if w.PeriodicalDamage > 0 {
+		effect := w.PeriodicalDamageWeaponEffect
+		if effect == "" {
+			effect = w.WeaponEffect
+		}
+		class := w.PeriodicalDamageWeaponClass
+		if class == "" {
+			class = w.WeaponClass
+		}
+		ret.BurnDamage = applyDamage(w.PeriodicalDamage, t.modifier(effect), armourFor(t, class), w.MinimumDamage)
+	}
+
+	// salvo weapons fire numRounds with firePause between them and then reload
+	rounds := 1
+	cycle := w.FirePause
+	if w.NumRounds > 0 && w.ReloadTime > 0 {
+		rounds = w.NumRounds
+		cycle = w.ReloadTime + (w.NumRounds-1)*w.FirePause
+	}
+	if cycle < 1 {
+		cycle = 1
+	}
+	cycleSeconds := (time.Duration(cycle) * statTimeUnit).Seconds()
+	ret.DPS = float64(ret.DamagePerShot*rounds) / cycleSeconds
+	burnTime := (time.Duration(w.PeriodicalDamageTime) * statTimeUnit).Seconds()
+	if ret.BurnDamage > 0 && burnTime > 0 {
+		// target keeps burning while it is being hit so burn adds up to its full rate
+		ret.DPS += float64(ret.BurnDamage) * math.Min(1, burnTime/cycleSeconds)
+	}
+	ret.ExpectedDPS = ret.DPS * float64(ret.HitChance) / 100
+	if ret.DamagePerShot > 0 {
+		ret.ShotsToKill = (t.hitpoints + ret.DamagePerShot - 1) / ret.DamagePerShot
+	}
+	if ret.ExpectedDPS > 0 {
+		ret.TimeToKill = time.Duration(float64(t.hitpoints) / ret.ExpectedDPS * float64(time.Second))
+	}
+	return ret, nil
+}
+
+func armourFor(t combatTarget, weaponClass string) int {
+	if weaponClass == "HEAT" {
+		return t.armourHeat
+	}
+	return t.armourKinetic
+}
+
+// applyDamage mirrors game objDamage: modifier is applied first, then armour
+// reduces damage down to minimumDamage percent but never below 1
+func applyDamage(damage, modifier, armour, minimumDamage int) int {
+	d := damage * modifier / 100
+	ret := d - armour
+	if floor := d * minimumDamage / 100; ret < floor {
+		ret = floor
+	}
+	if ret < 1 {
+		ret = 1
+	}
+	return ret
+}
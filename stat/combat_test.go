@@ -0,0 +1,112 @@
+package stat
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestApplyDamage(t *testing.T) {
+	tests := []struct {
+		damage, modifier, armour, minimum int
+		want                              int
+	}{
+		{10, 100, 5, 33, 5},
+		// armour can not take damage below minimum percent
+		{10, 100, 10, 33, 3},
+		// modifier is applied before armour
+		{35, 125, 10, 33, 33},
+		{28, 110, 4, 33, 26},
+		// at least 1
+		{4, 100, 15, 10, 1},
+		{0, 100, 0, 33, 1},
+	}
+	for _, tt := range tests {
+		if got := applyDamage(tt.damage, tt.modifier, tt.armour, tt.minimum); got != tt.want {
+			t.Errorf("applyDamage(%d, %d, %d, %d) = %d, want %d", tt.damage, tt.modifier, tt.armour, tt.minimum, got, tt.want)
+		}
+	}
+}
+
+func TestWeaponVsDesign(t *testing.T) {
+	s := loadTestStats(t)
+	// Viper Wheels MG: 65 body + 65 wheels (100% of body) + 75 weapon hitpoints,
+	// 10 kinetic and 4 thermal armour
+	target := Design{Body: "Body1REC", Propulsion: "wheeled01", Weapons: []string{"MG1Mk1"}}
+	tests := []struct {
+		weapon      string
+		rng         int
+		damage      int
+		burn        int
+		hitChance   int
+		dps         float64
+		shotsToKill int
+	}{
+		// 10 damage, anti personnel 100% vs wheels, armour leaves 33%, fires every 0.5s
+		{"MG1Mk1", 0, 3, 0, 55, 6, 69},
+		{"MG1Mk1", 512, 3, 0, 75, 6, 69},
+		// 35 damage, all rounder 125% vs wheels, fires every 3s
+		{"Cannon1Mk1", 0, 33, 0, 55, 11, 7},
+		// 28 damage and 20 burn, flamer 110% vs wheels, heat armour, fires every 2s
+		// and burns for 5s so burn adds its full rate
+		{"Flame1Mk1", 0, 26, 18, 40, 13 + 18, 8},
+		// 29 damage, artillery 90% vs wheels, 8 round salvo with 0.1s pause
+		// and 16.5s reload
+		{"Rocket-MRL", 0, 16, 0, 52, 16 * 8 / 17.2, 13},
+	}
+	for _, tt := range tests {
+		r, err := s.WeaponVsDesign(tt.weapon, nil, target, nil, tt.rng)
+		if err != nil {
+			t.Errorf("%s: %v", tt.weapon, err)
+			continue
+		}
+		if r.HitPoints != 205 || r.DamagePerShot != tt.damage || r.BurnDamage != tt.burn || r.HitChance != tt.hitChance || r.ShotsToKill != tt.shotsToKill {
+			t.Errorf("%s at %d: got hitpoints %d damage %d burn %d hit %d shots %d, want 205 %d %d %d %d",
+				tt.weapon, tt.rng, r.HitPoints, r.DamagePerShot, r.BurnDamage, r.HitChance, r.ShotsToKill, tt.damage, tt.burn, tt.hitChance, tt.shotsToKill)
+		}
+		if math.Abs(r.DPS-tt.dps) > 1e-9 || math.Abs(r.ExpectedDPS-tt.dps*float64(tt.hitChance)/100) > 1e-9 {
+			t.Errorf("%s at %d: dps %f expected %f, want %f", tt.weapon, tt.rng, r.DPS, r.ExpectedDPS, tt.dps)
+		}
+	}
+
+	r, _ := s.WeaponVsDesign("MG1Mk1", nil, target, nil, 0)
+	if want := 205 / 3.3; math.Abs(r.TimeToKill.Seconds()-want) > 1e-6 {
+		t.Errorf("time to kill %v, want %fs", r.TimeToKill, want)
+	}
+	if _, err := s.WeaponVsDesign("MG1Mk1", nil, target, nil, 1000); !errors.Is(err, ErrOutOfRange) {
+		t.Errorf("beyond long range: got %v", err)
+	}
+	if _, err := s.WeaponVsDesign("Flame1Mk1", nil, target, nil, 32); !errors.Is(err, ErrOutOfRange) {
+		t.Errorf("below minimum range: got %v", err)
+	}
+	if _, err := s.WeaponVsDesign("NoSuchWeapon", nil, target, nil, 0); !errors.Is(err, ErrUnknownWeapon) {
+		t.Errorf("unknown weapon: got %v", err)
+	}
+}
+
+func TestWeaponVsStructure(t *testing.T) {
+	s := loadTestStats(t)
+	// hardcrete wall: 700 hitpoints, 15 armour, HARD strength
+	tests := []struct {
+		weapon      string
+		damage      int
+		shotsToKill int
+	}{
+		// all rounder 100% vs hard
+		{"Cannon1Mk1", 20, 35},
+		// anti personnel 40% vs hard leaves 4 damage, minimum 33% of it rounds to 1
+		{"MG1Mk1", 1, 700},
+	}
+	for _, tt := range tests {
+		r, err := s.WeaponVsStructure(tt.weapon, nil, "A0HardcreteMk1Wall", nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.HitPoints != 700 || r.DamagePerShot != tt.damage || r.ShotsToKill != tt.shotsToKill {
+			t.Errorf("%s: got hitpoints %d damage %d shots %d, want 700 %d %d", tt.weapon, r.HitPoints, r.DamagePerShot, r.ShotsToKill, tt.damage, tt.shotsToKill)
+		}
+	}
+	if _, err := s.WeaponVsStructure("MG1Mk1", nil, "NoSuchStructure", nil, 0); !errors.Is(err, ErrUnknownStructure) {
+		t.Errorf("unknown structure: got %v", err)
+	}
+}
@@ -0,0 +1,305 @@
+package stat
+
+import "fmt"
+
+// Design is a droid template referenced by component ids, empty or ZNULL ids mean "no component"
+type Design struct {
+	Body       string
+	Brain      string
+	Propulsion string
+	Repair     string
+	ECM        string
+	Sensor     string
+	Construct  string
+	Weapons    []string
+}
+
+type DesignReport struct {
+	Problems    []string
+	Power       int
+	BuildPoints int
+	Weight      int
+	Speed       int
+	HitPoints   int
+}
+
+func (r *DesignReport) Legal() bool {
+	return len(r.Problems) == 0
+}
+
+func (r *DesignReport) problem(format string, a ...any) {
+	r.Problems = append(r.Problems, fmt.Sprintf(format, a...))
+}
+
+// StartingComponents are enabled for every player by multiplay rules before any research
+var StartingComponents = []string{
+	"ZNULLBODY", "ZNULLBRAIN", "ZNULLPROP", "ZNULLREPAIR", "ZNULLECM", "ZNULLSENSOR", "ZNULLCONSTRUCT", "ZNULLWEAPON",
+	"Body1REC", "wheeled01", "Spade1Mk1", "SensorTurret1Mk1", "MG1Mk1",
+	"CyborgLightBody", "CyborgLegs", "CyborgSpade",
+}
+
+func isNullComponent(id string) bool {
+	return id == "" || len(id) > 5 && id[:5] == "ZNULL"
+}
+
+// AvailableComponents returns components player can use after completed research
+func (u *Upgrades) AvailableComponents() map[string]bool {
+	ret := map[string]bool{}
+	for _, c := range StartingComponents {
+		ret[c] = true
+	}
+	for id := range u.Completed {
+		r, ok := u.stats.Research[id]
+		if !ok {
+			continue
+		}
+		for _, c := range r.ResultComponents {
+			ret[c] = true
+		}
+	}
+	for id := range u.Completed {
+		r, ok := u.stats.Research[id]
+		if !ok {
+			continue
+		}
+		for _, c := range r.RedComponents {
+			delete(ret, c)
+		}
+	}
+	return ret
+}
+
+// IsTemplate reports whether design is one of predefined templates (cyborgs, transporters)
+func (s *Stats) IsTemplate(d Design) bool {
+	for _, t := range s.Template {
+		if t.Body != d.Body || t.Propulsion != d.Propulsion || len(t.Weapons) != len(d.Weapons) {
+			continue
+		}
+		if !sameComponent(t.Brain, d.Brain) || !sameComponent(t.Repair, d.Repair) ||
+			!sameComponent(t.ECM, d.ECM) || !sameComponent(t.Sensor, d.Sensor) ||
+			!sameComponent(t.Construct, d.Construct) {
+			continue
+		}
+		match := true
+		for i := range t.Weapons {
+			if t.Weapons[i] != d.Weapons[i] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+func sameComponent(a, b string) bool {
+	if isNullComponent(a) && isNullComponent(b) {
+		return true
+	}
+	return a == b
+}
+
+// CheckDesign validates design the same way game design screen does and calculates
+// its price, weight and speed. Upgrades may be nil, then base stats are used and
+// research availability is not checked.
+func (s *Stats) CheckDesign(d Design, u *Upgrades) DesignReport {
+	ret := DesignReport{}
+	var avail map[string]bool
+	if u == nil {
+		u = s.NewUpgrades()
+	} else {
+		avail = u.AvailableComponents()
+	}
+	// game fills sensor, ecm and repair with default (non turret) parts
+	// itself, these are neither designable nor have to be researched
+	defaults := map[string]bool{}
+	body := u.Body(d.Body)
+	prop := u.Propulsion(d.Propulsion)
+	if isNullComponent(d.Body) || body == nil {
+		ret.problem("no body %q", d.Body)
+	}
+	if isNullComponent(d.Propulsion) || prop == nil {
+		ret.problem("no propulsion %q", d.Propulsion)
+	}
+	comps := []*Component{}
+	if body != nil {
+		comps = append(comps, &body.Component)
+	}
+	if prop != nil {
+		comps = append(comps, &prop.Component)
+	}
+	systems := 0
+	if !isNullComponent(d.Brain) {
+		if b := u.Brain(d.Brain); b != nil {
+			comps = append(comps, &b.Component)
+			systems++
+		} else {
+			ret.problem("unknown brain %q", d.Brain)
+		}
+	}
+	if !isNullComponent(d.Repair) {
+		if r := u.Repair(d.Repair); r != nil {
+			comps = append(comps, &r.Component)
+			if r.Location == "TURRET" {
+				systems++
+			} else {
+				defaults[r.ID] = true
+			}
+		} else {
+			ret.problem("unknown repair %q", d.Repair)
+		}
+	}
+	if !isNullComponent(d.ECM) {
+		if e := u.ECM(d.ECM); e != nil {
+			comps = append(comps, &e.Component)
+			if e.Location == "TURRET" {
+				systems++
+			} else {
+				defaults[e.ID] = true
+			}
+		} else {
+			ret.problem("unknown ecm %q", d.ECM)
+		}
+	}
+	if !isNullComponent(d.Sensor) {
+		if se := u.Sensor(d.Sensor); se != nil {
+			comps = append(comps, &se.Component)
+			if se.Location == "TURRET" {
+				systems++
+			} else {
+				defaults[se.ID] = true
+			}
+		} else {
+			ret.problem("unknown sensor %q", d.Sensor)
+		}
+	}
+	if !isNullComponent(d.Construct) {
+		if c := u.Construct(d.Construct); c != nil {
+			comps = append(comps, &c.Component)
+			systems++
+		} else {
+			ret.problem("unknown construct %q", d.Construct)
+		}
+	}
+	weapons := []*Weapon{}
+	for _, wid := range d.Weapons {
+		if isNullComponent(wid) {
+			continue
+		}
+		if w := u.Weapon(wid); w != nil {
+			weapons = append(weapons, w)
+			comps = append(comps, &w.Component)
+		} else {
+			ret.problem("unknown weapon %q", wid)
+		}
+	}
+	if avail != nil {
+		for _, c := range append([]string{d.Body, d.Brain, d.Propulsion, d.Repair, d.ECM, d.Sensor, d.Construct}, d.Weapons...) {
+			if !isNullComponent(c) && !defaults[c] && !avail[c] {
+				ret.problem("component %s is not researched", c)
+			}
+		}
+	}
+	if body == nil || prop == nil {
+		return ret
+	}
+
+	isTemplate := s.IsTemplate(d)
+	if !isTemplate {
+		for _, c := range comps {
+			if c.Designable == 0 && !isNullComponent(c.ID) && !defaults[c.ID] {
+				ret.problem("component %s is not designable", c.ID)
+			}
+		}
+	}
+	isCyborg := body.Class == "Cyborgs"
+	isTransport := body.Class == "Transports"
+	isVTOL := false
+	if pt, ok := s.PropulsionType[prop.Type]; ok {
+		isVTOL = pt.FlightName == "AIR"
+	}
+	if isCyborg != (prop.ID == "CyborgLegs") {
+		ret.problem("cyborg body %s with propulsion %s", body.ID, prop.ID)
+	}
+	if isTransport {
+		if !isVTOL {
+			ret.problem("transport with ground propulsion %s", prop.ID)
+		}
+		if len(weapons) != 0 || systems != 0 {
+			ret.problem("transport with turrets")
+		}
+	} else if len(weapons) == 0 && systems == 0 {
+		ret.problem("no turret")
+	}
+	if len(weapons) > body.WeaponSlots {
+		ret.problem("%d weapons on body with %d weapon slots", len(weapons), body.WeaponSlots)
+	}
+	if systems > 1 {
+		ret.problem("more than one system turret")
+	}
+	if !isNullComponent(d.Brain) && len(weapons) != 1 {
+		ret.problem("commander needs one weapon turret")
+	}
+	if systems != 0 && len(weapons) != 0 && isNullComponent(d.Brain) {
+		ret.problem("system and weapon turrets mixed")
+	}
+	if isVTOL && systems != 0 {
+		ret.problem("VTOL with system turret")
+	}
+	for _, w := range weapons {
+		if isVTOL && w.NumAttackRuns == 0 {
+			ret.problem("VTOL with non-VTOL weapon %s", w.ID)
+		}
+		if isVTOL && (w.Movement == "INDIRECT" || w.Movement == "HOMING-INDIRECT") {
+			ret.problem("VTOL with indirect weapon %s", w.ID)
+		}
+		if !isVTOL && w.NumAttackRuns > 0 {
+			ret.problem("VTOL weapon %s on ground propulsion", w.ID)
+		}
+	}
+
+	// propulsion price, build points and weight are percentage of the body ones
+	ret.Power = body.BuildPower + prop.BuildPower*body.BuildPower/100
+	ret.BuildPoints = body.BuildPoints + prop.BuildPoints*body.BuildPoints/100
+	ret.Weight = body.Weight + prop.Weight*body.Weight/100
+	ret.HitPoints = body.HitPoints + prop.HitPoints + body.HitPoints*prop.HitPointPctOfBody/100
+	for _, c := range comps[2:] {
+		ret.Power += c.BuildPower
+		ret.BuildPoints += c.BuildPoints
+		ret.Weight += c.Weight
+		ret.HitPoints += c.HitPoints
+	}
+	ret.HitPoints = ret.HitPoints * body.HitPointPct / 100
+	ret.Speed = s.designSpeed(body, prop, ret.Weight)
+	return ret
+}
+
+func (s *Stats) designSpeed(body *Body, prop *Propulsion, weight int) int {
+	mult := 100
+	pt, ok := s.PropulsionType[prop.Type]
+	if ok {
+		mult = pt.Multiplier
+	}
+	if weight < 1 {
+		weight = 1
+	}
+	speed := mult * body.PowerOutput / weight
+	if ok && pt.FlightName == "AIR" {
+		switch body.Size {
+		case "HEAVY":
+			speed /= 4
+		case "MEDIUM":
+			speed = speed * 3 / 4
+		}
+	}
+	// engine output bonus if output is above weight
+	if body.PowerOutput > weight {
+		speed = speed * 3 / 2
+	}
+	if speed > prop.Speed {
+		speed = prop.Speed
+	}
+	return speed
+}
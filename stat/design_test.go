@@ -0,0 +1,65 @@
+package stat
+
+import "testing"
+
+func TestCheckDesignDefaultParts(t *testing.T) {
+	s := loadTestStats(t)
+	tests := []struct {
+		name string
+		d    Design
+	}{
+		{"default sensor", Design{Body: "Body5REC", Propulsion: "HalfTrack", Sensor: "DefaultSensor1Mk1", Weapons: []string{"MG3Mk1"}}},
+		{"auto repair", Design{Body: "Body5REC", Propulsion: "HalfTrack", Sensor: "DefaultSensor1Mk1", Repair: "AutoRepair", Weapons: []string{"MG3Mk1"}}},
+	}
+	for _, tt := range tests {
+		r := s.CheckDesign(tt.d, nil)
+		if len(r.Problems) != 0 {
+			t.Errorf("%s: unexpected problems %v", tt.name, r.Problems)
+		}
+	}
+}
+
+func TestCheckDesignProblems(t *testing.T) {
+	s := loadTestStats(t)
+	r := s.CheckDesign(Design{Body: "Body5REC", Propulsion: "HalfTrack", Sensor: "DefaultSensor1Mk1"}, nil)
+	if len(r.Problems) != 1 || r.Problems[0] != "no turret" {
+		t.Errorf("got %v, want no turret", r.Problems)
+	}
+	// default parts are not counted as researched
+	r = s.CheckDesign(Design{Body: "Body5REC", Propulsion: "HalfTrack", Sensor: "DefaultSensor1Mk1", Weapons: []string{"MG3Mk1"}}, s.NewUpgrades())
+	for _, p := range r.Problems {
+		if p == "component DefaultSensor1Mk1 is not researched" {
+			t.Errorf("default sensor reported: %v", r.Problems)
+		}
+	}
+}
+
+func TestCheckDesignTemplates(t *testing.T) {
+	s := loadTestStats(t)
+	checked := 0
+	for id, tp := range s.Template {
+		// scavenger and special templates use bodies and propulsions
+		// player can not design with
+		body, prop := s.Body[tp.Body], s.Propulsion[tp.Propulsion]
+		if body == nil || prop == nil || body.Designable == 0 || prop.Designable == 0 {
+			continue
+		}
+		checked++
+		r := s.CheckDesign(Design{
+			Body:       tp.Body,
+			Brain:      tp.Brain,
+			Propulsion: tp.Propulsion,
+			Repair:     tp.Repair,
+			ECM:        tp.ECM,
+			Sensor:     tp.Sensor,
+			Construct:  tp.Construct,
+			Weapons:    tp.Weapons,
+		}, nil)
+		if !r.Legal() {
+			t.Errorf("template %s: %v", id, r.Problems)
+		}
+	}
+	if checked == 0 {
+		t.Error("no designable templates")
+	}
+}
@@ -0,0 +1,230 @@
+package stat
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+const (
+	DiffAdded    = "added"
+	DiffRemoved  = "removed"
+	DiffModified = "modified"
+)
+
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   any    `json:"old,omitempty"`
+	New   any    `json:"new,omitempty"`
+}
+
+type ObjectDiff struct {
+	ID      string        `json:"id"`
+	Status  string        `json:"status"`
+	Changes []FieldChange `json:"changes,omitempty"`
+}
+
+type FileDiff struct {
+	File    string       `json:"file"`
+	Objects []ObjectDiff `json:"objects"`
+}
+
+type StatsDiff struct {
+	Old   string     `json:"old"`
+	New   string     `json:"new"`
+	Files []FileDiff `json:"files"`
+	// Skipped lists json files that are not objects keyed by id
+	Skipped []string `json:"skipped,omitempty"`
+}
+
+// errNotStatsObject is returned for json files that hold something other than object
+var errNotStatsObject = errors.New("not a stats object")
+
+// DiffStatsDirs compares every json file of two stats directories object by
+// object, files that are not json objects are listed as skipped
+func DiffStatsDirs(oldDir, newDir string) (*StatsDiff, error) {
+	ret := &StatsDiff{Old: oldDir, New: newDir, Files: []FileDiff{}}
+	files := map[string]bool{}
+	for _, dir := range []string{oldDir, newDir} {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			if !e.IsDir() && strings.HasSuffix(e.Name(), ".json") {
+				files[e.Name()] = true
+			}
+		}
+	}
+	names := make([]string, 0, len(files))
+	for k := range files {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		o, err := loadRawStatsFile(path.Join(oldDir, name))
+		if errors.Is(err, errNotStatsObject) {
+			ret.Skipped = append(ret.Skipped, name)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		n, err := loadRawStatsFile(path.Join(newDir, name))
+		if errors.Is(err, errNotStatsObject) {
+			ret.Skipped = append(ret.Skipped, name)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		fd := FileDiff{File: name, Objects: DiffObjects(o, n)}
+		if len(fd.Objects) > 0 {
+			ret.Files = append(ret.Files, fd)
+		}
+	}
+	return ret, nil
+}
+
+func loadRawStatsFile(p string) (map[string]any, error) {
+	b, err := os.ReadFile(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return map[string]any{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var v any
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	ret, ok := v.(map[string]any)
+	if !ok {
+		return nil, errNotStatsObject
+	}
+	return ret, nil
+}
+
+// DiffObjects compares two stats files decoded into generic maps keyed by object id
+func DiffObjects(o, n map[string]any) []ObjectDiff {
+	ids := map[string]bool{}
+	for k := range o {
+		ids[k] = true
+	}
+	for k := range n {
+		ids[k] = true
+	}
+	keys := make([]string, 0, len(ids))
+	for k := range ids {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	ret := []ObjectDiff{}
+	for _, id := range keys {
+		ov, oh := o[id]
+		nv, nh := n[id]
+		switch {
+		case !oh:
+			ret = append(ret, ObjectDiff{ID: id, Status: DiffAdded})
+		case !nh:
+			ret = append(ret, ObjectDiff{ID: id, Status: DiffRemoved})
+		default:
+			of := map[string]any{}
+			nf := map[string]any{}
+			flattenStats("", ov, of)
+			flattenStats("", nv, nf)
+			if ch := diffFields(of, nf); len(ch) > 0 {
+				ret = append(ret, ObjectDiff{ID: id, Status: DiffModified, Changes: ch})
+			}
+		}
+	}
+	return ret
+}
+
+func flattenStats(prefix string, v any, out map[string]any) {
+	m, ok := v.(map[string]any)
+	if !ok {
+		out[prefix] = v
+		return
+	}
+	for k, vv := range m {
+		if prefix != "" {
+			k = prefix + "." + k
+		}
+		flattenStats(k, vv, out)
+	}
+}
+
+func diffFields(o, n map[string]any) []FieldChange {
+	fields := map[string]bool{}
+	for k := range o {
+		fields[k] = true
+	}
+	for k := range n {
+		fields[k] = true
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	ret := []FieldChange{}
+	for _, k := range keys {
+		if !reflect.DeepEqual(o[k], n[k]) {
+			ret = append(ret, FieldChange{Field: k, Old: o[k], New: n[k]})
+		}
+	}
+	return ret
+}
+
+func (d *StatsDiff) Markdown() string {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "# Stats diff\n\n`%s` → `%s`\n", d.Old, d.New)
+	if len(d.Files) == 0 {
+		b.WriteString("\nNo changes.\n")
+	}
+	if len(d.Skipped) > 0 {
+		fmt.Fprintf(b, "\nSkipped files that are not stats objects: `%s`\n", strings.Join(d.Skipped, "`, `"))
+	}
+	for _, f := range d.Files {
+		fmt.Fprintf(b, "\n## %s\n", f.File)
+		for _, status := range []string{DiffAdded, DiffRemoved} {
+			ids := []string{}
+			for _, o := range f.Objects {
+				if o.Status == status {
+					ids = append(ids, "`"+o.ID+"`")
+				}
+			}
+			if len(ids) > 0 {
+				fmt.Fprintf(b, "\n**%s**: %s\n", strings.ToUpper(status[:1])+status[1:], strings.Join(ids, ", "))
+			}
+		}
+		for _, o := range f.Objects {
+			if o.Status != DiffModified {
+				continue
+			}
+			fmt.Fprintf(b, "\n### %s\n\n| Field | Old | New |\n| --- | --- | --- |\n", o.ID)
+			for _, c := range o.Changes {
+				fmt.Fprintf(b, "| %s | %s | %s |\n", c.Field, markdownValue(c.Old), markdownValue(c.New))
+			}
+		}
+	}
+	return b.String()
+}
+
+func markdownValue(v any) string {
+	if v == nil {
+		return ""
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return "`" + strings.ReplaceAll(string(b), "|", "\\|") + "`"
+}
@@ -0,0 +1,66 @@
+package stat
+
+import (
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeStatsDir(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(path.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestDiffStatsDirs(t *testing.T) {
+	oldDir := writeStatsDir(t, map[string]string{
+		"body.json": `{
+	"Body1REC": {"id": "Body1REC", "hitpoints": 65, "armourKinetic": 10},
+	"Body5REC": {"id": "Body5REC", "hitpoints": 200},
+	"Body4ABT": {"id": "Body4ABT", "hitpoints": 100}
+}`,
+		"propulsion.json": `{"wheeled01": {"id": "wheeled01", "speed": 175}}`,
+		"mods.json":       `["balance"]`,
+	})
+	newDir := writeStatsDir(t, map[string]string{
+		"body.json": `{
+	"Body1REC": {"id": "Body1REC", "hitpoints": 70, "armourKinetic": 10},
+	"Body5REC": {"id": "Body5REC", "hitpoints": 200},
+	"Body8MBT": {"id": "Body8MBT", "hitpoints": 150}
+}`,
+		"propulsion.json": `{"wheeled01": {"id": "wheeled01", "speed": 175}}`,
+		"mods.json":       `["balance", "music"]`,
+		"weapons.json":    `{"MG1Mk1": {"id": "MG1Mk1", "damage": 10}}`,
+	})
+	d, err := DiffStatsDirs(oldDir, newDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []FileDiff{
+		{File: "body.json", Objects: []ObjectDiff{
+			{ID: "Body1REC", Status: DiffModified, Changes: []FieldChange{{Field: "hitpoints", Old: float64(65), New: float64(70)}}},
+			{ID: "Body4ABT", Status: DiffRemoved},
+			{ID: "Body8MBT", Status: DiffAdded},
+		}},
+		{File: "weapons.json", Objects: []ObjectDiff{{ID: "MG1Mk1", Status: DiffAdded}}},
+	}
+	if !reflect.DeepEqual(d.Files, want) {
+		t.Errorf("diff %+v, want %+v", d.Files, want)
+	}
+	if !reflect.DeepEqual(d.Skipped, []string{"mods.json"}) {
+		t.Errorf("skipped %v", d.Skipped)
+	}
+	md := d.Markdown()
+	for _, s := range []string{"## body.json", "**Added**: `Body8MBT`", "**Removed**: `Body4ABT`", "| hitpoints | `65` | `70` |", "`mods.json`"} {
+		if !strings.Contains(md, s) {
+			t.Errorf("markdown has no %q:\n%s", s, md)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package stat
+
+// Order holds ids in order game assigns indexes to them, these indexes are
+// what gets sent over the network and recorded in replays
+type Order struct {
+	Components [COMP_NUMCOMPONENTS][]string
+	Research   []string
+	Structure  []string
+}
+
+func lookupIndex(order []string, index int) (string, bool) {
+	if index < 0 || index >= len(order) {
+		return "", false
+	}
+	return order[index], true
+}
+
+func (s *Stats) ComponentID(t COMPONENT_TYPE, index int) (string, bool) {
+	if t < 0 || t >= COMP_NUMCOMPONENTS {
+		return "", false
+	}
+	return lookupIndex(s.Order.Components[t], index)
+}
+
+func (s *Stats) ResearchID(index int) (string, bool) {
+	return lookupIndex(s.Order.Research, index)
+}
+
+func (s *Stats) StructureID(index int) (string, bool) {
+	return lookupIndex(s.Order.Structure, index)
+}
+
+// RefToID resolves stat reference (STAT_* base + index) to id
+func (s *Stats) RefToID(ref uint32) (string, bool) {
+	t, i := RefToComponent(ref)
+	if t >= 0 {
+		return s.ComponentID(t, i)
+	}
+	switch ref & STAT_MASK {
+	case STAT_RESEARCH:
+		return s.ResearchID(i)
+	case STAT_STRUCTURE:
+		return s.StructureID(i)
+	}
+	return "", false
+}
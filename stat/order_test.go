@@ -0,0 +1,149 @@
+package stat
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"testing"
+)
+
+// statsDir is stats data shipped with replay-dumper
+const statsDir = "../replay-dumper/data/mp/stats"
+
+func loadTestStats(t *testing.T, mods ...string) *Stats {
+	t.Helper()
+	s, err := LoadStatsWithMods(statsDir, mods...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestComponentOrder(t *testing.T) {
+	s := loadTestStats(t)
+	tests := []struct {
+		t     COMPONENT_TYPE
+		index int
+		id    string
+	}{
+		{COMP_BODY, 0, "ZNULLBODY"},
+		{COMP_BODY, 16, "Body11ABT"},
+		{COMP_BODY, 24, "Body5REC"},
+		{COMP_BRAIN, 0, "ZNULLBRAIN"},
+		{COMP_PROPULSION, 0, "ZNULLPROP"},
+		{COMP_PROPULSION, 3, "HalfTrack"},
+		{COMP_SENSOR, 2, "DefaultSensor1Mk1"},
+		{COMP_CONSTRUCT, 1, "Spade1Mk1"},
+		{COMP_WEAPON, 20, "Cannon1Mk1"},
+		{COMP_WEAPON, 71, "MG3Mk1"},
+		// null component is swapped with first sorted one, not moved
+		{COMP_REPAIRUNIT, 0, "ZNULLREPAIR"},
+		{COMP_REPAIRUNIT, 5, "AutoRepair"},
+		{COMP_ECM, 0, "ZNULLECM"},
+		{COMP_ECM, 1, "RepairCentre"},
+		{COMP_ECM, 2, "ECM1TurretMk1"},
+	}
+	for _, tt := range tests {
+		id, ok := s.ComponentID(tt.t, tt.index)
+		if !ok || id != tt.id {
+			t.Errorf("component %d index %d: got %q (%v), want %q", tt.t, tt.index, id, ok, tt.id)
+		}
+	}
+	if _, ok := s.ComponentID(COMP_BODY, len(s.Order.Components[COMP_BODY])); ok {
+		t.Error("index past the end resolved")
+	}
+}
+
+func TestStatsOrder(t *testing.T) {
+	s := loadTestStats(t)
+	tests := []struct {
+		lookup func(int) (string, bool)
+		index  int
+		id     string
+	}{
+		{s.StructureID, 0, "A0ADemolishStructure"},
+		{s.StructureID, 1, "A0BaBaBunker"},
+		{s.ResearchID, 0, "R-Comp-CommandTurret01"},
+		{s.ResearchID, 2, "R-Comp-SynapticLink"},
+	}
+	for _, tt := range tests {
+		if id, ok := tt.lookup(tt.index); !ok || id != tt.id {
+			t.Errorf("index %d: got %q (%v), want %q", tt.index, id, ok, tt.id)
+		}
+	}
+}
+
+func TestLoadStatsWithMods(t *testing.T) {
+	mod := t.TempDir()
+	files := map[string]string{
+		"body.json": `{
+	"Body5REC": {"id": "Body5REC", "name": "Modded Cobra", "hitpoints": 999},
+	"AAAModBody": {"id": "AAAModBody", "name": "Mod body"}
+}`,
+		"repair.json": `{"ZNULLREPAIR": {"id": "ZNULLREPAIR", "name": "Mod null"}}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(path.Join(mod, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	base := loadTestStats(t)
+	s := loadTestStats(t, mod)
+
+	b := s.Body["Body5REC"]
+	if b.Name != "Modded Cobra" || b.HitPoints != 999 {
+		t.Errorf("override not applied: %q %d", b.Name, b.HitPoints)
+	}
+	// fields not present in mod are kept from base
+	if b.BuildPower != base.Body["Body5REC"].BuildPower || b.BuildPower == 0 {
+		t.Errorf("base field lost: build power %d", b.BuildPower)
+	}
+	// new component sorts first so it takes null swap slot and shifts the rest
+	if id, _ := s.ComponentID(COMP_BODY, 0); id != "ZNULLBODY" {
+		t.Errorf("index 0 is %q", id)
+	}
+	if id, _ := s.ComponentID(COMP_BODY, 25); id != "Body5REC" {
+		t.Errorf("Body5REC index is not shifted, index 25 is %q", id)
+	}
+	if len(s.Order.Components[COMP_BODY]) != len(base.Order.Components[COMP_BODY])+1 {
+		t.Errorf("mod body not added")
+	}
+	if s.Repair["ZNULLREPAIR"].Name != "Mod null" {
+		t.Errorf("null component override not applied")
+	}
+	if got, want := s.Order.Components[COMP_REPAIRUNIT], base.Order.Components[COMP_REPAIRUNIT]; len(got) != len(want) || got[0] != "ZNULLREPAIR" {
+		t.Errorf("repair order changed: %v", got)
+	}
+}
+
+func TestLoadStatsMissingComponentFile(t *testing.T) {
+	base := t.TempDir()
+	entries, err := os.ReadDir(statsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if e.Name() == "brain.json" {
+			continue
+		}
+		b, err := os.ReadFile(path.Join(statsDir, e.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path.Join(base, e.Name()), b, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := LoadStats(base); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("got %v, want missing brain.json error", err)
+	}
+	// file present in mod only is enough
+	s, err := LoadStatsWithMods(base, statsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := s.ComponentID(COMP_BRAIN, 1); id != "CommandBrain01" {
+		t.Errorf("brain index 1 is %q", id)
+	}
+}
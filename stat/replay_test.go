@@ -0,0 +1,149 @@
+package stat_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/maxsupermanhd/go-wz/packet"
+	"github.com/maxsupermanhd/go-wz/replay"
+	"github.com/maxsupermanhd/go-wz/stat"
+	"github.com/maxsupermanhd/go-wz/wznet"
+)
+
+// netU32 writes value in game variable length encoding, values below 178
+// take single byte
+func netU32(b *bytes.Buffer, v uint32) {
+	if v >= 178 {
+		panic("value does not fit single byte")
+	}
+	b.WriteByte(byte(v))
+}
+
+func netString(b *bytes.Buffer, s string) {
+	netU32(b, uint32(len(s)))
+	for _, c := range s {
+		binary.Write(b, binary.BigEndian, uint16(c))
+	}
+}
+
+// replayFile lays out replay the way game records it: settings, embedded
+// map, messages of player 0 and end chunk
+func replayFile(t *testing.T, messages map[byte][]byte, order []byte) []byte {
+	t.Helper()
+	b := &bytes.Buffer{}
+	b.WriteString("WZrp")
+	settings, err := json.Marshal(replay.ReplaySettings{Major: 4, Minor: 4, ReplayFormatVer: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	binary.Write(b, binary.BigEndian, uint32(len(settings)))
+	b.Write(settings)
+	binary.Write(b, binary.BigEndian, uint32(1)) // embedded map version
+	binary.Write(b, binary.BigEndian, uint32(0)) // no embedded map
+	for _, pt := range order {
+		b.WriteByte(0)
+		b.WriteByte(pt)
+		netU32(b, uint32(len(messages[pt])))
+		b.Write(messages[pt])
+	}
+	b.WriteByte(0)
+	b.WriteByte(wznet.REPLAY_ENDED)
+	netU32(b, 0)
+	end := []byte(`{"gameTimeElapsed":60000}`)
+	binary.Write(b, binary.BigEndian, uint32(len(end)))
+	b.Write(end)
+	binary.Write(b, binary.BigEndian, uint32(0))
+	return b.Bytes()
+}
+
+func TestReplayWireIndexes(t *testing.T) {
+	// commander factory order and research start laid out like game sends
+	// them, indexes are positions in game component and research lists
+	manufacture := &bytes.Buffer{}
+	manufacture.WriteByte(0)                               // player
+	netU32(manufacture, 42)                                // factory id
+	manufacture.WriteByte(wznet.STRUCTUREINFO_MANUFACTURE) // info
+	netString(manufacture, "Command Turret Cobra Tracks")
+	netU32(manufacture, 7) // template id
+	netU32(manufacture, 0) // DROID_WEAPON
+	manufacture.Write([]byte{
+		24, // Body5REC
+		1,  // CommandBrain01
+		9,  // tracked01
+		0,  // ZNULLREPAIR
+		0,  // ZNULLECM
+		0,  // ZNULLSENSOR
+		0,  // ZNULLCONSTRUCT
+		1,  // weapon count
+	})
+	netU32(manufacture, 29) // CommandTurret1
+	research := &bytes.Buffer{}
+	research.WriteByte(0) // player
+	research.WriteByte(1) // start
+	netU32(research, 43)  // research facility id
+	netU32(research, 0)   // R-Comp-CommandTurret01
+
+	raw := replayFile(t, map[byte][]byte{
+		wznet.GAME_STRUCTUREINFO:  manufacture.Bytes(),
+		wznet.GAME_RESEARCHSTATUS: research.Bytes(),
+	}, []byte{wznet.GAME_STRUCTUREINFO, wznet.GAME_RESEARCHSTATUS})
+	r, err := replay.ReadReplay(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Messages) != 3 {
+		t.Fatalf("decoded %d messages", len(r.Messages))
+	}
+
+	s, err := stat.LoadStats("../replay-dumper/data/mp/stats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	si, ok := r.Messages[0].NetPacket.(packet.PkGameStructInfo)
+	if !ok {
+		t.Fatalf("first message is %T", r.Messages[0].NetPacket)
+	}
+	id := func(c stat.COMPONENT_TYPE, index uint32) string {
+		id, _ := s.ComponentID(c, int(index))
+		return id
+	}
+	d := stat.Design{
+		Body:       id(stat.COMP_BODY, uint32(si.Droid.Body)),
+		Brain:      id(stat.COMP_BRAIN, uint32(si.Droid.Brain)),
+		Propulsion: id(stat.COMP_PROPULSION, uint32(si.Droid.Propulsion)),
+		Repair:     id(stat.COMP_REPAIRUNIT, uint32(si.Droid.Repairunit)),
+		ECM:        id(stat.COMP_ECM, uint32(si.Droid.Ecm)),
+		Sensor:     id(stat.COMP_SENSOR, uint32(si.Droid.Sensor)),
+		Construct:  id(stat.COMP_CONSTRUCT, uint32(si.Droid.Construct)),
+	}
+	for _, w := range si.DroidWeapons {
+		d.Weapons = append(d.Weapons, id(stat.COMP_WEAPON, w))
+	}
+	want := stat.Design{
+		Body:       "Body5REC",
+		Brain:      "CommandBrain01",
+		Propulsion: "tracked01",
+		Repair:     "ZNULLREPAIR",
+		ECM:        "ZNULLECM",
+		Sensor:     "ZNULLSENSOR",
+		Construct:  "ZNULLCONSTRUCT",
+		Weapons:    []string{"CommandTurret1"},
+	}
+	if !reflect.DeepEqual(d, want) {
+		t.Errorf("decoded design %+v, want %+v", d, want)
+	}
+	if rep := s.CheckDesign(d, nil); !rep.Legal() {
+		t.Errorf("commander reported illegal: %v", rep.Problems)
+	}
+
+	rs, ok := r.Messages[1].NetPacket.(packet.PkGameResearchStatus)
+	if !ok {
+		t.Fatalf("second message is %T", r.Messages[1].NetPacket)
+	}
+	if topic, _ := s.ResearchID(int(rs.Topic)); topic != "R-Comp-CommandTurret01" {
+		t.Errorf("research topic %d is %q", rs.Topic, topic)
+	}
+}
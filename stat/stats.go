@@ -0,0 +1,385 @@
+package stat
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+)
+
+type Component struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	BuildPower  int    `json:"buildPower"`
+	BuildPoints int    `json:"buildPoints"`
+	Weight      int    `json:"weight"`
+	HitPoints   int    `json:"hitpoints"`
+	Designable  int    `json:"designable"`
+	DroidType   string `json:"droidType"`
+}
+
+type Body struct {
+	Component
+	Class         string `json:"class"`
+	Size          string `json:"size"`
+	WeaponSlots   int    `json:"weaponSlots"`
+	PowerOutput   int    `json:"powerOutput"`
+	ArmourKinetic int    `json:"armourKinetic"`
+	ArmourHeat    int    `json:"armourHeat"`
+	Resistance    int    `json:"resistance"`
+	// HitPointPct is not present in stats files, game starts it at 100
+	HitPointPct int `json:"-"`
+}
+
+type Brain struct {
+	Component
+	Turret              string   `json:"turret"`
+	BaseCommandLimit    int      `json:"maxDroids"`
+	CommandLimitByLevel int      `json:"maxDroidsMult"`
+	RankThresholds      []int    `json:"rankThresholds"`
+	Ranks               []string `json:"ranks"`
+}
+
+type Propulsion struct {
+	Component
+	Type              string `json:"type"`
+	Speed             int    `json:"speed"`
+	HitPointPctOfBody int    `json:"hitpointPctOfBody"`
+	Acceleration      int    `json:"acceleration"`
+	Deceleration      int    `json:"deceleration"`
+	SkidDeceleration  int    `json:"skidDeceleration"`
+	SpinSpeed         int    `json:"spinSpeed"`
+	SpinAngle         int    `json:"spinAngle"`
+	TurnSpeed         int    `json:"turnSpeed"`
+}
+
+type PropulsionType struct {
+	FlightName string `json:"flightName"`
+	Multiplier int    `json:"multiplier"`
+}
+
+type Sensor struct {
+	Component
+	Location string `json:"location"`
+	Type     string `json:"type"`
+	Range    int    `json:"range"`
+	Power    int    `json:"power"`
+}
+
+type ECM struct {
+	Component
+	Location string `json:"location"`
+	Range    int    `json:"range"`
+}
+
+type Repair struct {
+	Component
+	Location     string `json:"location"`
+	RepairPoints int    `json:"repairPoints"`
+	Time         int    `json:"time"`
+}
+
+type Construct struct {
+	Component
+	ConstructPoints int `json:"constructPoints"`
+}
+
+type Weapon struct {
+	Component
+	WeaponClass                    string `json:"weaponClass"`
+	WeaponSubClass                 string `json:"weaponSubClass"`
+	WeaponEffect                   string `json:"weaponEffect"`
+	Movement                       string `json:"movement"`
+	Flags                          string `json:"flags"`
+	Damage                         int    `json:"damage"`
+	MinimumDamage                  int    `json:"minimumDamage"`
+	RadiusDamage                   int    `json:"radiusDamage"`
+	Radius                         int    `json:"radius"`
+	PeriodicalDamage               int    `json:"periodicalDamage"`
+	PeriodicalDamageTime           int    `json:"periodicalDamageTime"`
+	PeriodicalDamageRadius         int    `json:"periodicalDamageRadius"`
+	PeriodicalDamageWeaponClass    string `json:"periodicalDamageWeaponClass"`
+	PeriodicalDamageWeaponEffect   string `json:"periodicalDamageWeaponEffect"`
+	PeriodicalDamageWeaponSubClass string `json:"periodicalDamageWeaponSubClass"`
+	FirePause                      int    `json:"firePause"`
+	ReloadTime                     int    `json:"reloadTime"`
+	NumRounds                      int    `json:"numRounds"`
+	NumAttackRuns                  int    `json:"numAttackRuns"`
+	NumExplosions                  int    `json:"numExplosions"`
+	LongRange                      int    `json:"longRange"`
+	ShortRange                     int    `json:"shortRange"`
+	MinRange                       int    `json:"minRange"`
+	LongHit                        int    `json:"longHit"`
+	ShortHit                       int    `json:"shortHit"`
+	FlightSpeed                    int    `json:"flightSpeed"`
+	Penetrate                      int    `json:"penetrate"`
+}
+
+type Structure struct {
+	ID                     string   `json:"id"`
+	Name                   string   `json:"name"`
+	Type                   string   `json:"type"`
+	Strength               string   `json:"strength"`
+	BuildPoints            int      `json:"buildPoints"`
+	BuildPower             int      `json:"buildPower"`
+	Width                  int      `json:"width"`
+	Breadth                int      `json:"breadth"`
+	Height                 int      `json:"height"`
+	HitPoints              int      `json:"hitpoints"`
+	Armour                 int      `json:"armour"`
+	Thermal                int      `json:"thermal"`
+	Resistance             int      `json:"resistance"`
+	PowerPoints            int      `json:"powerPoints"`
+	ModulePowerPoints      int      `json:"modulePowerPoints"`
+	ProductionPoints       int      `json:"productionPoints"`
+	ModuleProductionPoints int      `json:"moduleProductionPoints"`
+	ResearchPoints         int      `json:"researchPoints"`
+	ModuleResearchPoints   int      `json:"moduleResearchPoints"`
+	RepairPoints           int      `json:"repairPoints"`
+	RearmPoints            int      `json:"rearmPoints"`
+	Weapons                []string `json:"weapons"`
+	SensorID               string   `json:"sensorID"`
+	EcmID                  string   `json:"ecmID"`
+	CombinesWithWall       bool     `json:"combinesWithWall"`
+	UserLimits             []int    `json:"userLimits"`
+}
+
+type ResearchResult struct {
+	Class           string `json:"class"`
+	Parameter       string `json:"parameter"`
+	FilterParameter string `json:"filterParameter"`
+	FilterValue     string `json:"filterValue"`
+	Value           int    `json:"value"`
+}
+
+type Research struct {
+	ID                 string           `json:"id"`
+	Name               string           `json:"name"`
+	StatID             string           `json:"statID"`
+	MsgName            string           `json:"msgName"`
+	IconID             string           `json:"iconID"`
+	SubgroupIconID     string           `json:"subgroupIconID"`
+	KeyTopic           int              `json:"keyTopic"`
+	TechCode           int              `json:"techCode"`
+	DisabledWhen       int              `json:"disabledWhen"`
+	ResearchPoints     int              `json:"researchPoints"`
+	ResearchPower      int              `json:"researchPower"`
+	RequiredResearch   []string         `json:"requiredResearch"`
+	RequiredStructures []string         `json:"requiredStructures"`
+	ResultComponents   []string         `json:"resultComponents"`
+	ResultStructures   []string         `json:"resultStructures"`
+	RedComponents      []string         `json:"redComponents"`
+	RedStructures      []string         `json:"redStructures"`
+	ReplacedComponents []string         `json:"replacedComponents"`
+	Results            []ResearchResult `json:"results"`
+}
+
+type Template struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Type       string   `json:"type"`
+	Available  bool     `json:"available"`
+	Body       string   `json:"body"`
+	Brain      string   `json:"brain"`
+	Propulsion string   `json:"propulsion"`
+	Repair     string   `json:"repair"`
+	ECM        string   `json:"ecm"`
+	Sensor     string   `json:"sensor"`
+	Construct  string   `json:"construct"`
+	Weapons    []string `json:"weapons"`
+}
+
+// Stats holds game stats as they are laid out in data/mp/stats, keyed by id
+type Stats struct {
+	Body              map[string]*Body
+	Brain             map[string]*Brain
+	Propulsion        map[string]*Propulsion
+	PropulsionType    map[string]*PropulsionType
+	Sensor            map[string]*Sensor
+	ECM               map[string]*ECM
+	Repair            map[string]*Repair
+	Construct         map[string]*Construct
+	Weapon            map[string]*Weapon
+	Structure         map[string]*Structure
+	Research          map[string]*Research
+	Template          map[string]*Template
+	WeaponModifier    map[string]map[string]int
+	StructureModifier map[string]map[string]int
+	Order             Order
+}
+
+// LoadStats reads stats directory. Component files are required as wire
+// indexes depend on them, other missing files are left empty
+func LoadStats(dir string) (*Stats, error) {
+	return LoadStatsWithMods(dir)
+}
+
+// LoadStatsWithMods reads stats directory and merges stats of mods over it the
+// way game does: fields of mod objects replace base ones, new objects are added
+func LoadStatsWithMods(dir string, mods ...string) (*Stats, error) {
+	dirs := append([]string{dir}, mods...)
+	s := &Stats{}
+	var err error
+	if s.Body, s.Order.Components[COMP_BODY], err = loadComponentFile[Body](dirs, "body.json", "ZNULLBODY"); err != nil {
+		return nil, err
+	}
+	for _, b := range s.Body {
+		b.HitPointPct = 100
+	}
+	if s.Brain, s.Order.Components[COMP_BRAIN], err = loadComponentFile[Brain](dirs, "brain.json", "ZNULLBRAIN"); err != nil {
+		return nil, err
+	}
+	if s.Propulsion, s.Order.Components[COMP_PROPULSION], err = loadComponentFile[Propulsion](dirs, "propulsion.json", "ZNULLPROP"); err != nil {
+		return nil, err
+	}
+	if s.PropulsionType, _, err = loadStatsFile[PropulsionType](dirs, "propulsiontype.json", false); err != nil {
+		return nil, err
+	}
+	if s.Sensor, s.Order.Components[COMP_SENSOR], err = loadComponentFile[Sensor](dirs, "sensor.json", "ZNULLSENSOR"); err != nil {
+		return nil, err
+	}
+	if s.ECM, s.Order.Components[COMP_ECM], err = loadComponentFile[ECM](dirs, "ecm.json", "ZNULLECM"); err != nil {
+		return nil, err
+	}
+	if s.Repair, s.Order.Components[COMP_REPAIRUNIT], err = loadComponentFile[Repair](dirs, "repair.json", "ZNULLREPAIR"); err != nil {
+		return nil, err
+	}
+	if s.Construct, s.Order.Components[COMP_CONSTRUCT], err = loadComponentFile[Construct](dirs, "construction.json", "ZNULLCONSTRUCT"); err != nil {
+		return nil, err
+	}
+	if s.Weapon, s.Order.Components[COMP_WEAPON], err = loadComponentFile[Weapon](dirs, "weapons.json", "ZNULLWEAPON"); err != nil {
+		return nil, err
+	}
+	if s.Structure, s.Order.Structure, err = loadStatsFile[Structure](dirs, "structure.json", true); err != nil {
+		return nil, err
+	}
+	if s.Research, s.Order.Research, err = loadStatsFile[Research](dirs, "research.json", true); err != nil {
+		return nil, err
+	}
+	if s.Template, _, err = loadStatsFile[Template](dirs, "templates.json", false); err != nil {
+		return nil, err
+	}
+	if s.WeaponModifier, err = loadModifierFile(dirs, "weaponmodifier.json"); err != nil {
+		return nil, err
+	}
+	if s.StructureModifier, err = loadModifierFile(dirs, "structuremodifier.json"); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// loadRawStatsFiles reads file from every directory and merges objects field
+// by field, required file must be present in at least one directory
+func loadRawStatsFiles(dirs []string, name string, required bool) (map[string]map[string]json.RawMessage, error) {
+	ret := map[string]map[string]json.RawMessage{}
+	found := false
+	for _, dir := range dirs {
+		b, err := os.ReadFile(path.Join(dir, name))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		found = true
+		objs := map[string]map[string]json.RawMessage{}
+		if err := json.Unmarshal(b, &objs); err != nil {
+			return nil, err
+		}
+		for id, fields := range objs {
+			o, ok := ret[id]
+			if !ok {
+				ret[id] = fields
+				continue
+			}
+			for k, v := range fields {
+				o[k] = v
+			}
+		}
+	}
+	if required && !found {
+		return nil, &fs.PathError{Op: "open", Path: path.Join(dirs[0], name), Err: fs.ErrNotExist}
+	}
+	return ret, nil
+}
+
+// loadStatsFile returns objects and their ids in order game enumerates
+// them (sorted by bytes, as json objects are stored in std::map)
+func loadStatsFile[T any](dirs []string, name string, required bool) (map[string]*T, []string, error) {
+	raw, err := loadRawStatsFiles(dirs, name, required)
+	if err != nil {
+		return nil, nil, err
+	}
+	ret := map[string]*T{}
+	order := make([]string, 0, len(raw))
+	for id, fields := range raw {
+		b, err := json.Marshal(fields)
+		if err != nil {
+			return nil, nil, err
+		}
+		var o T
+		if err := json.Unmarshal(b, &o); err != nil {
+			return nil, nil, err
+		}
+		ret[id] = &o
+		order = append(order, id)
+	}
+	sort.Strings(order)
+	return ret, order, nil
+}
+
+type component interface {
+	component() *Component
+}
+
+func (c *Component) component() *Component {
+	return c
+}
+
+// loadComponentFile is loadStatsFile that puts null component first like game
+// does, null component is created when stats do not have it. File itself is
+// required, without it every following wire index would be off
+func loadComponentFile[T any, PT interface {
+	*T
+	component
+}](dirs []string, name string, null string) (map[string]*T, []string, error) {
+	ret, order, err := loadStatsFile[T](dirs, name, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	i := sort.SearchStrings(order, null)
+	if i == len(order) || order[i] != null {
+		var o T
+		c := PT(&o).component()
+		c.ID = null
+		c.Name = null
+		ret[null] = &o
+		order = append([]string{null}, order...)
+	} else {
+		// game swaps null component with the first one instead of moving it
+		order[0], order[i] = order[i], order[0]
+	}
+	return ret, order, nil
+}
+
+func loadModifierFile(dirs []string, name string) (map[string]map[string]int, error) {
+	raw, err := loadRawStatsFiles(dirs, name, false)
+	if err != nil {
+		return nil, err
+	}
+	ret := map[string]map[string]int{}
+	for effect, fields := range raw {
+		m := map[string]int{}
+		for k, v := range fields {
+			var val int
+			if err := json.Unmarshal(v, &val); err != nil {
+				return nil, err
+			}
+			m[k] = val
+		}
+		ret[effect] = m
+	}
+	return ret, nil
+}
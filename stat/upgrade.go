@@ -0,0 +1,410 @@
+package stat
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+var (
+	ErrUnknownResearch = errors.New("unknown research")
+)
+
+// Upgrades tracks stat upgrades of one player the same way multiplay rules.js
+// does it: every result adds ceil(base * value / 100) to the upgraded parameter
+type Upgrades struct {
+	stats     *Stats
+	Completed map[string]bool
+	values    map[string]map[string]map[string][]int
+}
+
+type upgradable interface {
+	upgradeParams() map[string][]*int
+	upgradeFilter(param string) (string, bool)
+}
+
+func (c *Component) upgradeFilter(param string) (string, bool) {
+	switch param {
+	case "Id":
+		return c.ID, true
+	case "Name":
+		return c.Name, true
+	}
+	return "", false
+}
+
+func (c *Component) upgradeParams() map[string][]*int {
+	return map[string][]*int{
+		"HitPoints":   {&c.HitPoints},
+		"BuildPower":  {&c.BuildPower},
+		"BuildPoints": {&c.BuildPoints},
+		"Weight":      {&c.Weight},
+	}
+}
+
+func (b *Body) upgradeFilter(param string) (string, bool) {
+	switch param {
+	case "BodyClass":
+		return b.Class, true
+	case "Size":
+		return b.Size, true
+	}
+	return b.Component.upgradeFilter(param)
+}
+
+func (b *Body) upgradeParams() map[string][]*int {
+	ret := b.Component.upgradeParams()
+	ret["Armour"] = []*int{&b.ArmourKinetic}
+	ret["Thermal"] = []*int{&b.ArmourHeat}
+	ret["Resistance"] = []*int{&b.Resistance}
+	ret["Power"] = []*int{&b.PowerOutput}
+	ret["HitPointPct"] = []*int{&b.HitPointPct}
+	return ret
+}
+
+func (b *Brain) upgradeParams() map[string][]*int {
+	ret := b.Component.upgradeParams()
+	ret["BaseCommandLimit"] = []*int{&b.BaseCommandLimit}
+	ret["CommandLimitByLevel"] = []*int{&b.CommandLimitByLevel}
+	ranks := make([]*int, len(b.RankThresholds))
+	for i := range b.RankThresholds {
+		ranks[i] = &b.RankThresholds[i]
+	}
+	ret["RankThresholds"] = ranks
+	return ret
+}
+
+func (p *Propulsion) upgradeParams() map[string][]*int {
+	ret := p.Component.upgradeParams()
+	ret["HitPointPctOfBody"] = []*int{&p.HitPointPctOfBody}
+	ret["Speed"] = []*int{&p.Speed}
+	return ret
+}
+
+func (s *Sensor) upgradeParams() map[string][]*int {
+	ret := s.Component.upgradeParams()
+	ret["Range"] = []*int{&s.Range}
+	return ret
+}
+
+func (e *ECM) upgradeParams() map[string][]*int {
+	ret := e.Component.upgradeParams()
+	ret["Range"] = []*int{&e.Range}
+	return ret
+}
+
+func (r *Repair) upgradeParams() map[string][]*int {
+	ret := r.Component.upgradeParams()
+	ret["RepairPoints"] = []*int{&r.RepairPoints}
+	return ret
+}
+
+func (c *Construct) upgradeParams() map[string][]*int {
+	ret := c.Component.upgradeParams()
+	ret["ConstructorPoints"] = []*int{&c.ConstructPoints}
+	return ret
+}
+
+func (w *Weapon) upgradeFilter(param string) (string, bool) {
+	switch param {
+	case "ImpactClass":
+		return w.WeaponSubClass, true
+	case "ImpactType":
+		return w.WeaponClass, true
+	case "Effect":
+		return w.WeaponEffect, true
+	}
+	return w.Component.upgradeFilter(param)
+}
+
+func (w *Weapon) upgradeParams() map[string][]*int {
+	ret := w.Component.upgradeParams()
+	ret["Damage"] = []*int{&w.Damage}
+	ret["MinimumDamage"] = []*int{&w.MinimumDamage}
+	ret["RadiusDamage"] = []*int{&w.RadiusDamage}
+	ret["Radius"] = []*int{&w.Radius}
+	ret["RepeatDamage"] = []*int{&w.PeriodicalDamage}
+	ret["RepeatTime"] = []*int{&w.PeriodicalDamageTime}
+	ret["RepeatRadius"] = []*int{&w.PeriodicalDamageRadius}
+	ret["FirePause"] = []*int{&w.FirePause}
+	ret["ReloadTime"] = []*int{&w.ReloadTime}
+	ret["HitChance"] = []*int{&w.LongHit}
+	ret["ShortHitChance"] = []*int{&w.ShortHit}
+	ret["LongRange"] = []*int{&w.LongRange}
+	ret["ShortRange"] = []*int{&w.ShortRange}
+	ret["MinRange"] = []*int{&w.MinRange}
+	return ret
+}
+
+// UpgradeType is what game exposes as Building.Type to research filters
+func (s *Structure) UpgradeType() string {
+	switch s.Type {
+	case "DEFENSE", "WALL", "CORNER WALL", "GENERIC", "GATE":
+		return "Wall"
+	case "DEMOLISH":
+		return "Demolish"
+	}
+	return "Structure"
+}
+
+func (s *Structure) upgradeFilter(param string) (string, bool) {
+	switch param {
+	case "Type":
+		return s.UpgradeType(), true
+	case "Id":
+		return s.ID, true
+	case "Name":
+		return s.Name, true
+	}
+	return "", false
+}
+
+func (s *Structure) upgradeParams() map[string][]*int {
+	return map[string][]*int{
+		"HitPoints":              {&s.HitPoints},
+		"Armour":                 {&s.Armour},
+		"Thermal":                {&s.Thermal},
+		"Resistance":             {&s.Resistance},
+		"PowerPoints":            {&s.PowerPoints},
+		"ModulePowerPoints":      {&s.ModulePowerPoints},
+		"ProductionPoints":       {&s.ProductionPoints},
+		"ModuleProductionPoints": {&s.ModuleProductionPoints},
+		"ResearchPoints":         {&s.ResearchPoints},
+		"ModuleResearchPoints":   {&s.ModuleResearchPoints},
+		"RepairPoints":           {&s.RepairPoints},
+		"RearmPoints":            {&s.RearmPoints},
+		"BuildPoints":            {&s.BuildPoints},
+		"BuildPower":             {&s.BuildPower},
+	}
+}
+
+// NewUpgrades returns upgrades of player that has not researched anything yet
+func (s *Stats) NewUpgrades() *Upgrades {
+	return &Upgrades{
+		stats:     s,
+		Completed: map[string]bool{},
+		values:    map[string]map[string]map[string][]int{},
+	}
+}
+
+// class returns stats of upgrade class with names used by research results
+func (s *Stats) class(c string) map[string]upgradable {
+	ret := map[string]upgradable{}
+	switch c {
+	case "Body":
+		for k, v := range s.Body {
+			ret[k] = v
+		}
+	case "Brain":
+		for k, v := range s.Brain {
+			ret[k] = v
+		}
+	case "Propulsion":
+		for k, v := range s.Propulsion {
+			ret[k] = v
+		}
+	case "Sensor":
+		for k, v := range s.Sensor {
+			ret[k] = v
+		}
+	case "ECM":
+		for k, v := range s.ECM {
+			ret[k] = v
+		}
+	case "Repair":
+		for k, v := range s.Repair {
+			ret[k] = v
+		}
+	case "Construct":
+		for k, v := range s.Construct {
+			ret[k] = v
+		}
+	case "Weapon":
+		for k, v := range s.Weapon {
+			ret[k] = v
+		}
+	case "Building":
+		for k, v := range s.Structure {
+			ret[k] = v
+		}
+	}
+	return ret
+}
+
+// ApplyResult applies single research result to every matching stat
+func (u *Upgrades) ApplyResult(r ResearchResult) {
+	for id, st := range u.stats.class(r.Class) {
+		if r.FilterParameter != "" {
+			if v, ok := st.upgradeFilter(r.FilterParameter); !ok || v != r.FilterValue {
+				continue
+			}
+		}
+		base, ok := st.upgradeParams()[r.Parameter]
+		if !ok {
+			continue
+		}
+		cu, ok := u.values[r.Class]
+		if !ok {
+			cu = map[string]map[string][]int{}
+			u.values[r.Class] = cu
+		}
+		pu, ok := cu[id]
+		if !ok {
+			pu = map[string][]int{}
+			cu[id] = pu
+		}
+		vals, ok := pu[r.Parameter]
+		if !ok {
+			vals = make([]int, len(base))
+			pu[r.Parameter] = vals
+		}
+		for i, b := range base {
+			if *b > 0 {
+				vals[i] += int(math.Ceil(float64(*b) * float64(r.Value) / 100))
+			}
+		}
+	}
+}
+
+// ApplyResearch applies all results of completed research, completing same topic twice does nothing
+func (u *Upgrades) ApplyResearch(id string) error {
+	res, ok := u.stats.Research[id]
+	if !ok {
+		return ErrUnknownResearch
+	}
+	if u.Completed[id] {
+		return nil
+	}
+	u.Completed[id] = true
+	for _, r := range res.Results {
+		u.ApplyResult(r)
+	}
+	return nil
+}
+
+func (u *Upgrades) apply(class, id string, st upgradable) {
+	params := st.upgradeParams()
+	for p, vals := range u.values[class][id] {
+		for i, v := range vals {
+			*params[p][i] += v
+		}
+	}
+}
+
+func (u *Upgrades) Body(id string) *Body {
+	b, ok := u.stats.Body[id]
+	if !ok {
+		return nil
+	}
+	r := *b
+	u.apply("Body", id, &r)
+	return &r
+}
+
+func (u *Upgrades) Brain(id string) *Brain {
+	b, ok := u.stats.Brain[id]
+	if !ok {
+		return nil
+	}
+	r := *b
+	r.RankThresholds = append([]int{}, b.RankThresholds...)
+	u.apply("Brain", id, &r)
+	return &r
+}
+
+func (u *Upgrades) Propulsion(id string) *Propulsion {
+	p, ok := u.stats.Propulsion[id]
+	if !ok {
+		return nil
+	}
+	r := *p
+	u.apply("Propulsion", id, &r)
+	return &r
+}
+
+func (u *Upgrades) Sensor(id string) *Sensor {
+	s, ok := u.stats.Sensor[id]
+	if !ok {
+		return nil
+	}
+	r := *s
+	u.apply("Sensor", id, &r)
+	return &r
+}
+
+func (u *Upgrades) ECM(id string) *ECM {
+	e, ok := u.stats.ECM[id]
+	if !ok {
+		return nil
+	}
+	r := *e
+	u.apply("ECM", id, &r)
+	return &r
+}
+
+func (u *Upgrades) Repair(id string) *Repair {
+	rp, ok := u.stats.Repair[id]
+	if !ok {
+		return nil
+	}
+	r := *rp
+	u.apply("Repair", id, &r)
+	return &r
+}
+
+func (u *Upgrades) Construct(id string) *Construct {
+	c, ok := u.stats.Construct[id]
+	if !ok {
+		return nil
+	}
+	r := *c
+	u.apply("Construct", id, &r)
+	return &r
+}
+
+func (u *Upgrades) Weapon(id string) *Weapon {
+	w, ok := u.stats.Weapon[id]
+	if !ok {
+		return nil
+	}
+	r := *w
+	u.apply("Weapon", id, &r)
+	return &r
+}
+
+func (u *Upgrades) Structure(id string) *Structure {
+	s, ok := u.stats.Structure[id]
+	if !ok {
+		return nil
+	}
+	r := *s
+	u.apply("Building", id, &r)
+	return &r
+}
+
+// ResearchEvent is completion of research topic by player at game time (ms)
+type ResearchEvent struct {
+	GameTime uint32
+	Player   int
+	Research string
+}
+
+// UpgradesAt replays research completions of player up to (including) gameTime
+func (s *Stats) UpgradesAt(events []ResearchEvent, player int, gameTime uint32) (*Upgrades, error) {
+	ev := make([]ResearchEvent, 0, len(events))
+	for _, e := range events {
+		if e.Player == player && e.GameTime <= gameTime {
+			ev = append(ev, e)
+		}
+	}
+	sort.SliceStable(ev, func(i, j int) bool {
+		return ev[i].GameTime < ev[j].GameTime
+	})
+	u := s.NewUpgrades()
+	for _, e := range ev {
+		if err := u.ApplyResearch(e.Research); err != nil {
+			return nil, err
+		}
+	}
+	return u, nil
+}
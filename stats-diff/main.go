@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/maxsupermanhd/go-wz/stat"
+)
+
+var (
+	oldDir = flag.String("old", "", "Path to old stats directory")
+	newDir = flag.String("new", "", "Path to new stats directory")
+	format = flag.String("format", "md", "Output format, md or json")
+	out    = flag.String("o", "-", "Path to write report to, - for stdout")
+)
+
+func main() {
+	log.SetFlags(0)
+	flag.Parse()
+	if *oldDir == "" || *newDir == "" {
+		flag.Usage()
+		os.Exit(1)
+	}
+	d, err := stat.DiffStatsDirs(*oldDir, *newDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var b []byte
+	switch *format {
+	case "md":
+		b = []byte(d.Markdown())
+	case "json":
+		b, err = json.MarshalIndent(d, "", "\t")
+		if err != nil {
+			log.Fatal(err)
+		}
+	default:
+		log.Fatalf("Unknown format %q", *format)
+	}
+	if *out == "-" {
+		os.Stdout.Write(b)
+		return
+	}
+	if err := os.WriteFile(*out, b, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,300 @@
+package wzmap
+
+import (
+	"image"
+	"math"
+	"strings"
+
+	"github.com/maxsupermanhd/go-wz/stat"
+)
+
+// structure types as in structure.json
+const (
+	StructHQ              = "HQ"
+	StructFactory         = "FACTORY"
+	StructCyborgFactory   = "CYBORG FACTORY"
+	StructVTOLFactory     = "VTOL FACTORY"
+	StructPowerGenerator  = "POWER GENERATOR"
+	StructResearch        = "RESEARCH"
+	StructResourceExtract = "RESOURCE EXTRACTOR"
+	StructDefense         = "DEFENSE"
+	StructWall            = "WALL"
+)
+
+// base game structure types used when no stats are given to analyzer
+var defaultStructureTypes = map[string]string{
+	"A0CommandCentre":     StructHQ,
+	"A0LightFactory":      StructFactory,
+	"A0CyborgFactory":     StructCyborgFactory,
+	"A0VTolFactory1":      StructVTOLFactory,
+	"A0PowerGen":          StructPowerGenerator,
+	"A0ResearchFacility":  StructResearch,
+	"A0ResourceExtractor": StructResourceExtract,
+	"A0RepairCentre3":     "REPAIR FACILITY",
+	"A0VtolPad":           "REARM PAD",
+	"A0FacMod1":           "FACTORY MODULE",
+	"A0PowMod1":           "POWER MODULE",
+	"A0ResearchModule1":   "RESEARCH MODULE",
+	"A0ComDroidControl":   "COMMAND RELAY",
+	"A0LasSatCommand":     "LASSAT",
+	"A0Sat-linkCentre":    "SAT UPLINK",
+	"A0TankTrap":          StructWall,
+	"A0HardcreteMk1Wall":  StructWall,
+	"A0HardcreteMk1CWall": "CORNER WALL",
+	"A0HardcreteMk1Gate":  "GATE",
+}
+
+type AnalyzeOptions struct {
+	// Stats are used to find structure types, base game names are used if nil
+	Stats *stat.Stats
+	// OilReach is radius in tiles around start position where oil counts as player's
+	OilReach int
+}
+
+var DefaultAnalyzeOptions = AnalyzeOptions{
+	OilReach: 12,
+}
+
+type SlotAnalysis struct {
+	Player int
+	// Start is start position in tiles, nil if slot has no start position,
+	// HQ is nil if player has no command center
+	Start      *image.Point
+	HQ         *image.Point
+	Units      int
+	Structures int
+	// StructureTypes counts structures by their type
+	StructureTypes map[string]int
+	// OilInReach counts oil resources and derricks within OilReach that are
+	// not closer to another player, 0 without start position
+	OilInReach int
+	// NearestEnemy is distance in tiles to closest other start position, 0 if
+	// there is none or slot has no start position
+	NearestEnemy float64
+	// BaseArea is bounding box of player structures in tiles
+	BaseArea image.Rectangle
+}
+
+type Analysis struct {
+	Slots []SlotAnalysis
+	// OilWells counts oil resources and derricks
+	OilWells int
+	// Oil are tiles of oil resources and derricks
+	Oil []image.Point
+	// Scavengers counts scavenger structures and units
+	Scavengers int
+	// Symmetries lists transformations map heights and oil match under:
+	// mirror-x, mirror-y, rotate-180, rotate-90, transpose, anti-transpose
+	Symmetries []string
+	// Paths are travel distances by propulsion class
+	Paths []PathAnalysis
+}
+
+// Analyze computes per slot counts and balance information of map
+func Analyze(m *Map, o AnalyzeOptions) *Analysis {
+	structType := func(name string) string {
+		if o.Stats != nil {
+			if s, ok := o.Stats.Structure[name]; ok {
+				return s.Type
+			}
+		}
+		if t, ok := defaultStructureTypes[name]; ok {
+			return t
+		}
+		// wall towers are defenses
+		if (strings.Contains(name, "Wall") && !strings.Contains(name, "Tower")) || strings.Contains(name, "TankTrap") {
+			return StructWall
+		}
+		return StructDefense
+	}
+	ret := &Analysis{Slots: []SlotAnalysis{}, Symmetries: []string{}}
+	starts := m.StartPositions()
+	for p, s := range starts {
+		slot := SlotAnalysis{Player: p, StructureTypes: map[string]int{}}
+		if s != nil {
+			pt := image.Pt(s.TileX(), s.TileY())
+			slot.Start = &pt
+		}
+		ret.Slots = append(ret.Slots, slot)
+	}
+	oils := []image.Point{}
+	for _, f := range m.Features {
+		if IsOilResource(f.Name) {
+			oils = append(oils, image.Pt(f.TileX(), f.TileY()))
+		}
+	}
+	for _, s := range m.Structures {
+		t := structType(s.Name)
+		if t == StructResourceExtract {
+			oils = append(oils, image.Pt(s.TileX(), s.TileY()))
+		}
+		if m.IsScavenger(s.Player) {
+			ret.Scavengers++
+			continue
+		}
+		if s.Player < 0 || s.Player >= len(ret.Slots) {
+			continue
+		}
+		slot := &ret.Slots[s.Player]
+		slot.Structures++
+		slot.StructureTypes[t]++
+		pt := image.Pt(s.TileX(), s.TileY())
+		if t == StructHQ && slot.HQ == nil {
+			slot.HQ = &pt
+		}
+		slot.BaseArea = slot.BaseArea.Union(image.Rectangle{pt, pt.Add(image.Pt(1, 1))})
+	}
+	for _, d := range m.Droids {
+		if m.IsScavenger(d.Player) {
+			ret.Scavengers++
+			continue
+		}
+		if d.Player >= 0 && d.Player < len(ret.Slots) {
+			ret.Slots[d.Player].Units++
+		}
+	}
+	oils = uniquePoints(oils)
+	ret.Oil = oils
+	ret.OilWells = len(oils)
+	for i := range ret.Slots {
+		slot := &ret.Slots[i]
+		if slot.Start == nil {
+			continue
+		}
+		for j, other := range ret.Slots {
+			if i == j || other.Start == nil {
+				continue
+			}
+			if d := tileDistance(*slot.Start, *other.Start); slot.NearestEnemy == 0 || d < slot.NearestEnemy {
+				slot.NearestEnemy = d
+			}
+		}
+		for _, oil := range oils {
+			d := tileDistance(*slot.Start, oil)
+			if d > float64(o.OilReach) {
+				continue
+			}
+			closest := true
+			for j, other := range ret.Slots {
+				if j != i && other.Start != nil && tileDistance(*other.Start, oil) < d {
+					closest = false
+					break
+				}
+			}
+			if closest {
+				slot.OilInReach++
+			}
+		}
+	}
+	ret.Symmetries = m.symmetries(oils)
+	ret.Paths = m.analyzePaths(ret, o.Stats)
+	return ret
+}
+
+// uniquePoints drops duplicates, derricks are placed over oil features on some maps
+func uniquePoints(p []image.Point) []image.Point {
+	seen := map[image.Point]bool{}
+	ret := []image.Point{}
+	for _, v := range p {
+		if !seen[v] {
+			seen[v] = true
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
+
+func tileDistance(a, b image.Point) float64 {
+	return math.Hypot(float64(a.X-b.X), float64(a.Y-b.Y))
+}
+
+// height difference (world units) still considered same when checking symmetry
+const symmetryHeightTolerance = 16
+
+// share of tiles that has to match for map to be considered symmetric
+const symmetryTileShare = 0.95
+
+func (m *Map) symmetries(oils []image.Point) []string {
+	w, h := m.Width-1, m.Height-1
+	transforms := []struct {
+		name   string
+		square bool
+		f      func(image.Point) image.Point
+	}{
+		{"mirror-x", false, func(p image.Point) image.Point { return image.Pt(w-p.X, p.Y) }},
+		{"mirror-y", false, func(p image.Point) image.Point { return image.Pt(p.X, h-p.Y) }},
+		{"rotate-180", false, func(p image.Point) image.Point { return image.Pt(w-p.X, h-p.Y) }},
+		{"rotate-90", true, func(p image.Point) image.Point { return image.Pt(w-p.Y, p.X) }},
+		{"transpose", true, func(p image.Point) image.Point { return image.Pt(p.Y, p.X) }},
+		{"anti-transpose", true, func(p image.Point) image.Point { return image.Pt(w-p.Y, h-p.X) }},
+	}
+	ret := []string{}
+	for _, t := range transforms {
+		if t.square && m.Width != m.Height {
+			continue
+		}
+		matching := 0
+		for y := 0; y < m.Height; y++ {
+			for x := 0; x < m.Width; x++ {
+				a, _ := m.Tile(x, y)
+				tp := t.f(image.Pt(x, y))
+				b, _ := m.Tile(tp.X, tp.Y)
+				if d := int(a.Height) - int(b.Height); d <= symmetryHeightTolerance && d >= -symmetryHeightTolerance {
+					matching++
+				}
+			}
+		}
+		if float64(matching) < symmetryTileShare*float64(len(m.Tiles)) {
+			continue
+		}
+		oilMatch := true
+		for _, oil := range oils {
+			tp := t.f(oil)
+			found := false
+			for _, other := range oils {
+				if tileDistance(tp, other) <= 1.5 {
+					found = true
+					break
+				}
+			}
+			if !found {
+				oilMatch = false
+				break
+			}
+		}
+		if oilMatch {
+			ret = append(ret, t.name)
+		}
+	}
+	return ret
+}
+
+// OilResources returns tiles of oil features and derricks without full
+// analysis, derricks are recognized by default structure names
+func (m *Map) OilResources() []image.Point {
+	oils := []image.Point{}
+	for _, f := range m.Features {
+		if IsOilResource(f.Name) {
+			oils = append(oils, image.Pt(f.TileX(), f.TileY()))
+		}
+	}
+	for _, s := range m.Structures {
+		if defaultStructureTypes[s.Name] == StructResourceExtract {
+			oils = append(oils, image.Pt(s.TileX(), s.TileY()))
+		}
+	}
+	return uniquePoints(oils)
+}
+
+// Scavengers counts scavenger structures and units
+func (m *Map) Scavengers() int {
+	ret := 0
+	for _, l := range [][]Object{m.Structures, m.Droids} {
+		for _, o := range l {
+			if m.IsScavenger(o.Player) {
+				ret++
+			}
+		}
+	}
+	return ret
+}
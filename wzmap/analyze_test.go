@@ -0,0 +1,64 @@
+package wzmap
+
+import (
+	"image"
+	"math"
+	"testing"
+)
+
+// emptySlotMap is 3 player map where slot 1 has no start position and oil
+// lies next to tile (0,0)
+func emptySlotMap() *Map {
+	m := &Map{Name: "3c-Test", Width: 16, Height: 16, Tiles: make([]Tile, 16*16), TerrainTypes: []TerrainType{TerrainSand}}
+	x, y := TileCenter(image.Pt(6, 6))
+	m.Structures = append(m.Structures, Object{ID: 1, Name: "A0CommandCentre", X: x, Y: y, Player: 0})
+	x, y = TileCenter(image.Pt(14, 14))
+	m.Structures = append(m.Structures, Object{ID: 2, Name: "A0CommandCentre", X: x, Y: y, Player: 2})
+	x, y = TileCenter(image.Pt(1, 1))
+	m.Features = append(m.Features, Object{ID: 3, Name: "OilResource", X: x, Y: y})
+	return m
+}
+
+func TestAnalyzeEmptySlot(t *testing.T) {
+	a := Analyze(emptySlotMap(), DefaultAnalyzeOptions)
+	if len(a.Slots) != 3 {
+		t.Fatalf("%d slots", len(a.Slots))
+	}
+	if a.Slots[0].Start == nil || *a.Slots[0].Start != image.Pt(6, 6) {
+		t.Errorf("slot 0 start %v", a.Slots[0].Start)
+	}
+	empty := a.Slots[1]
+	if empty.Start != nil || empty.NearestEnemy != 0 || empty.OilInReach != 0 {
+		t.Errorf("slot without start: start %v nearest enemy %f oil %d", empty.Start, empty.NearestEnemy, empty.OilInReach)
+	}
+	// oil at (1,1) is within reach of slot 0 now that empty slot does not claim it
+	if a.Slots[0].OilInReach != 1 {
+		t.Errorf("slot 0 oil in reach %d", a.Slots[0].OilInReach)
+	}
+	if d := a.Slots[0].NearestEnemy; d != tileDistance(image.Pt(6, 6), image.Pt(14, 14)) {
+		t.Errorf("slot 0 nearest enemy %f", d)
+	}
+}
+
+func TestAnalyzePathsEmptySlot(t *testing.T) {
+	a := Analyze(emptySlotMap(), DefaultAnalyzeOptions)
+	if len(a.Paths) == 0 {
+		t.Fatal("no path analysis")
+	}
+	for _, pa := range a.Paths {
+		for j := range a.Slots {
+			if pa.StartToStart[1][j] != Unreachable || pa.StartToStart[j][1] != Unreachable {
+				t.Errorf("%s: empty slot distance to %d is %f and %f", pa.Class, j, pa.StartToStart[1][j], pa.StartToStart[j][1])
+			}
+		}
+		if pa.StartToOil[1][0] != Unreachable {
+			t.Errorf("%s: empty slot distance to oil %f", pa.Class, pa.StartToOil[1][0])
+		}
+		if d := pa.StartToOil[0][0]; math.Abs(d-5*math.Sqrt2) > 1e-9 {
+			t.Errorf("%s: slot 0 distance to oil %f", pa.Class, d)
+		}
+		if pa.StartToStart[0][2] != pa.StartToStart[2][0] || math.Abs(pa.StartToStart[0][2]-8*math.Sqrt2) > 1e-9 {
+			t.Errorf("%s: start to start %f", pa.Class, pa.StartToStart[0][2])
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package wzmap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+const (
+	TileNumMask  = 0x01ff
+	TileTriFlip  = 0x0800
+	TileRotMask  = 0x3000
+	TileRotShift = 12
+	TileYFlip    = 0x4000
+	TileXFlip    = 0x8000
+)
+
+// ElevationScale is multiplier of uint8 heights used by game.map before version 40
+const ElevationScale = 2
+
+type Tile struct {
+	// Texture holds texture number together with flip and rotation bits
+	Texture uint16
+	// Height is in world units
+	Height uint16
+}
+
+func (t Tile) TextureID() int {
+	return int(t.Texture & TileNumMask)
+}
+
+// Rotation returns number of 90 degree turns
+func (t Tile) Rotation() int {
+	return int(t.Texture&TileRotMask) >> TileRotShift
+}
+
+func (t Tile) XFlip() bool {
+	return t.Texture&TileXFlip != 0
+}
+
+func (t Tile) YFlip() bool {
+	return t.Texture&TileYFlip != 0
+}
+
+func (t Tile) TriFlip() bool {
+	return t.Texture&TileTriFlip != 0
+}
+
+type TerrainType uint16
+
+const (
+	TerrainSand TerrainType = iota
+	TerrainSandyBrush
+	TerrainBakedEarth
+	TerrainGreenMud
+	TerrainRedBrush
+	TerrainPinkRock
+	TerrainRoad
+	TerrainWater
+	TerrainCliffFace
+	TerrainRubble
+	TerrainSheetIce
+	TerrainSlush
+	TerrainMax
+)
+
+func (t TerrainType) String() string {
+	switch t {
+	case TerrainSand:
+		return "sand"
+	case TerrainSandyBrush:
+		return "sandy brush"
+	case TerrainBakedEarth:
+		return "baked earth"
+	case TerrainGreenMud:
+		return "green mud"
+	case TerrainRedBrush:
+		return "red brush"
+	case TerrainPinkRock:
+		return "pink rock"
+	case TerrainRoad:
+		return "road"
+	case TerrainWater:
+		return "water"
+	case TerrainCliffFace:
+		return "cliff face"
+	case TerrainRubble:
+		return "rubble"
+	case TerrainSheetIce:
+		return "sheet ice"
+	case TerrainSlush:
+		return "slush"
+	}
+	return "unknown"
+}
+
+type Gateway struct {
+	X1, Y1, X2, Y2 uint8
+}
+
+type gameMapHeader struct {
+	Magic   [4]byte
+	Version uint32
+	Width   uint32
+	Height  uint32
+}
+
+// maximum map side game accepts
+const maxMapSide = 256
+
+func (m *Map) readGameMap(b []byte) error {
+	r := bytes.NewReader(b)
+	var h gameMapHeader
+	if err := readLE(r, &h); err != nil {
+		return err
+	}
+	if string(h.Magic[:]) != "map " {
+		return ErrWrongMagic
+	}
+	if h.Version < 10 || h.Version > 40 {
+		return ErrUnsupportedVersion
+	}
+	if h.Width == 0 || h.Height == 0 || h.Width > maxMapSide || h.Height > maxMapSide {
+		return ErrBadMapSize
+	}
+	m.Width = int(h.Width)
+	m.Height = int(h.Height)
+	m.Tiles = make([]Tile, m.Width*m.Height)
+	for i := range m.Tiles {
+		if err := readLE(r, &m.Tiles[i].Texture); err != nil {
+			return err
+		}
+		if h.Version >= 40 {
+			if err := readLE(r, &m.Tiles[i].Height); err != nil {
+				return err
+			}
+		} else {
+			var height uint8
+			if err := readLE(r, &height); err != nil {
+				return err
+			}
+			m.Tiles[i].Height = uint16(height) * ElevationScale
+		}
+	}
+	var gwVersion, gwCount uint32
+	if err := readLE(r, &gwVersion); err != nil {
+		// gateways are optional at the end of the file
+		if errors.Is(err, ErrTruncated) {
+			return nil
+		}
+		return err
+	}
+	if err := readLE(r, &gwCount); err != nil {
+		return err
+	}
+	if int(gwCount)*4 > r.Len() {
+		return ErrTruncated
+	}
+	m.Gateways = make([]Gateway, gwCount)
+	for i := range m.Gateways {
+		if err := readLE(r, &m.Gateways[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type terrainTypesHeader struct {
+	Magic   [4]byte
+	Version uint32
+	Count   uint32
+}
+
+// ReadTerrainTypes parses ttypes.ttp, result is indexed by tile texture number
+func ReadTerrainTypes(b []byte) ([]TerrainType, error) {
+	r := bytes.NewReader(b)
+	var h terrainTypesHeader
+	if err := readLE(r, &h); err != nil {
+		return nil, err
+	}
+	if string(h.Magic[:]) != "ttyp" {
+		return nil, ErrWrongMagic
+	}
+	if int(h.Count)*2 > r.Len() {
+		return nil, ErrTruncated
+	}
+	ret := make([]TerrainType, h.Count)
+	if err := readLE(r, ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+func readLE(r io.Reader, v any) error {
+	err := binary.Read(r, binary.LittleEndian, v)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return ErrTruncated
+	}
+	return err
+}
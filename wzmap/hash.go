@@ -0,0 +1,25 @@
+package wzmap
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+var ErrHashMismatch = errors.New("map hash mismatch")
+
+// Hash computes map hash the way game does it for GameOptions.Game.Hash,
+// it is hex encoded sha256 of the whole .wz archive
+func Hash(b []byte) string {
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
+
+// VerifyHash returns ErrHashMismatch if archive does not match expected hash
+func VerifyHash(b []byte, expected string) error {
+	if !strings.EqualFold(Hash(b), strings.TrimSpace(expected)) {
+		return ErrHashMismatch
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package wzmap
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestHash(t *testing.T) {
+	// sha256 of "abc"
+	const abc = "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"
+	if got := Hash([]byte("abc")); got != abc {
+		t.Errorf("hash %s, want %s", got, abc)
+	}
+}
+
+func TestVerifyHash(t *testing.T) {
+	archive := []byte("map archive")
+	h := Hash(archive)
+	tests := []struct {
+		expected string
+		err      error
+	}{
+		{h, nil},
+		{strings.ToUpper(h), nil},
+		{" " + h + "\n", nil},
+		{Hash([]byte("other map")), ErrHashMismatch},
+		{h[:32], ErrHashMismatch},
+		{"", ErrHashMismatch},
+	}
+	for _, tt := range tests {
+		if err := VerifyHash(archive, tt.expected); !errors.Is(err, tt.err) {
+			t.Errorf("expected hash %q: got %v, want %v", tt.expected, err, tt.err)
+		}
+	}
+	// tampered archive does not match original hash
+	tampered := append([]byte{}, archive...)
+	tampered[0] ^= 1
+	if err := VerifyHash(tampered, h); !errors.Is(err, ErrHashMismatch) {
+		t.Errorf("tampered archive: got %v", err)
+	}
+}
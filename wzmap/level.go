@@ -0,0 +1,139 @@
+package wzmap
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// Level is level.json of new map format
+type Level struct {
+	Name      string   `json:"name"`
+	Type      string   `json:"type"`
+	Players   int      `json:"players"`
+	Tileset   string   `json:"tileset"`
+	Authors   []string `json:"-"`
+	License   string   `json:"license"`
+	Created   string   `json:"-"`
+	Generator string   `json:"generator"`
+}
+
+// ReadLevel parses level.json, author may be a string, an object with name or a list of them
+func ReadLevel(b []byte) (*Level, error) {
+	l := &Level{}
+	type levelAlias Level
+	aliased := &struct {
+		Author            json.RawMessage `json:"author"`
+		AdditionalAuthors json.RawMessage `json:"additionalAuthors"`
+		Created           json.RawMessage `json:"created"`
+		*levelAlias
+	}{
+		levelAlias: (*levelAlias)(l),
+	}
+	if err := json.Unmarshal(b, aliased); err != nil {
+		return nil, err
+	}
+	l.Authors = append(readAuthors(aliased.Author), readAuthors(aliased.AdditionalAuthors)...)
+	var created string
+	if json.Unmarshal(aliased.Created, &created) == nil {
+		l.Created = created
+	} else {
+		var c struct {
+			Date string `json:"date"`
+		}
+		if json.Unmarshal(aliased.Created, &c) == nil {
+			l.Created = c.Date
+		}
+	}
+	return l, nil
+}
+
+func readAuthors(b json.RawMessage) []string {
+	if len(b) == 0 {
+		return nil
+	}
+	var s string
+	if json.Unmarshal(b, &s) == nil {
+		return []string{s}
+	}
+	var n struct {
+		Name string `json:"name"`
+	}
+	if json.Unmarshal(b, &n) == nil && n.Name != "" {
+		return []string{n.Name}
+	}
+	var l []json.RawMessage
+	if json.Unmarshal(b, &l) == nil {
+		ret := []string{}
+		for _, v := range l {
+			ret = append(ret, readAuthors(v)...)
+		}
+		return ret
+	}
+	return nil
+}
+
+// LegacyLevel is one level entry of .lev file
+type LegacyLevel struct {
+	Level   string
+	Players int
+	Type    int
+	Dataset string
+	Game    string
+	Data    []string
+}
+
+// Tileset guesses tileset from level dataset
+func (l LegacyLevel) Tileset() string {
+	d := strings.ToUpper(l.Dataset)
+	switch {
+	case strings.HasSuffix(d, "CAM_1"), strings.HasSuffix(d, "_C1"):
+		return "arizona"
+	case strings.HasSuffix(d, "CAM_2"), strings.HasSuffix(d, "_C2"):
+		return "urban"
+	case strings.HasSuffix(d, "CAM_3"), strings.HasSuffix(d, "_C3"):
+		return "rockies"
+	}
+	return ""
+}
+
+// ReadLegacyLevels parses .lev file, unknown keywords are ignored
+func ReadLegacyLevels(b []byte) []LegacyLevel {
+	ret := []LegacyLevel{}
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		key := fields[0]
+		val := strings.Trim(strings.Join(fields[1:], " "), `"`)
+		if key == "level" || key == "campaign" {
+			ret = append(ret, LegacyLevel{Level: val})
+			continue
+		}
+		if len(ret) == 0 {
+			continue
+		}
+		l := &ret[len(ret)-1]
+		switch key {
+		case "players":
+			l.Players, _ = strconv.Atoi(val)
+		case "type":
+			l.Type, _ = strconv.Atoi(val)
+		case "dataset":
+			l.Dataset = val
+		case "game":
+			l.Game = val
+		case "data":
+			l.Data = append(l.Data, val)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,69 @@
+package wzmap
+
+import (
+	"image"
+)
+
+// WorldToTile converts world coordinates (like PkGameDroidInfo.CoordX/CoordY) to tile coordinates
+func WorldToTile(x, y int) image.Point {
+	return image.Pt(floorDiv(x, TileUnits), floorDiv(y, TileUnits))
+}
+
+// TileCenter returns world coordinates of center of tile
+func TileCenter(t image.Point) (x, y int) {
+	return t.X*TileUnits + TileUnits/2, t.Y*TileUnits + TileUnits/2
+}
+
+func floorDiv(a, b int) int {
+	if a < 0 {
+		return -((-a + b - 1) / b)
+	}
+	return a / b
+}
+
+// Tile returns tile object is on
+func (o Object) Tile() image.Point {
+	return WorldToTile(o.X, o.Y)
+}
+
+// ObjectByID looks up map placed structure, droid or feature by id. It
+// resolves DestID of orders only if game gives map objects the ids stored in
+// map, which is assumed and not checked against recorded games, so result
+// should be treated as a hint.
+func (m *Map) ObjectByID(id uint32) *Object {
+	if id == 0 {
+		return nil
+	}
+	for _, l := range [][]Object{m.Structures, m.Droids, m.Features} {
+		for i := range l {
+			if l[i].ID == id {
+				return &l[i]
+			}
+		}
+	}
+	return nil
+}
+
+// OilAt resolves derrick build position (world units) to oil resource it is
+// built on. Derricks are placed at tile center so oil on the same tile is
+// preferred, neighbouring tiles are checked to tolerate rounding.
+func (m *Map) OilAt(x, y int) *Object {
+	t := WorldToTile(x, y)
+	var best *Object
+	bestDist := 0
+	for i := range m.Features {
+		f := &m.Features[i]
+		if !IsOilResource(f.Name) {
+			continue
+		}
+		d := f.Tile().Sub(t)
+		if d.X < -1 || d.X > 1 || d.Y < -1 || d.Y > 1 {
+			continue
+		}
+		dist := d.X*d.X + d.Y*d.Y
+		if best == nil || dist < bestDist {
+			best, bestDist = f, dist
+		}
+	}
+	return best
+}
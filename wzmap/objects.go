@@ -0,0 +1,226 @@
+package wzmap
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PlayerScavenger is Object.Player of scavenger owned objects
+const PlayerScavenger = -1
+
+type Object struct {
+	ID   uint32
+	Name string
+	// X, Y and Z are in world units
+	X int
+	Y int
+	Z int
+	// Direction is 0-65535 for full turn
+	Direction uint16
+	Player    int
+	// Modules is number of structure modules, only for structures
+	Modules int
+	// Template is droid template name, only for droids
+	Template string
+}
+
+// TileX returns x coordinate of tile object is on
+func (o Object) TileX() int {
+	return o.Tile().X
+}
+
+// TileY returns y coordinate of tile object is on
+func (o Object) TileY() int {
+	return o.Tile().Y
+}
+
+func (m *Map) readObjects(read func(string) ([]byte, error)) error {
+	files := []struct {
+		json, bjo, magic string
+		out              *[]Object
+	}{
+		{"struct.json", "struct.bjo", "stru", &m.Structures},
+		{"droid.json", "dinit.bjo", "dint", &m.Droids},
+		{"feature.json", "feat.bjo", "feat", &m.Features},
+	}
+	for _, f := range files {
+		b, err := read(f.json)
+		if err != nil {
+			return err
+		}
+		if b != nil {
+			if *f.out, err = ReadObjectsJSON(b); err != nil {
+				return err
+			}
+			continue
+		}
+		if b, err = read(f.bjo); err != nil {
+			return err
+		}
+		if b != nil {
+			if *f.out, err = ReadObjectsBJO(b, f.magic); err != nil {
+				return err
+			}
+			m.Legacy = true
+		}
+	}
+	return nil
+}
+
+type jsonObject struct {
+	ID       uint32          `json:"id"`
+	Name     string          `json:"name"`
+	Template string          `json:"template"`
+	Position []int           `json:"position"`
+	Rotation []int           `json:"rotation"`
+	Player   json.RawMessage `json:"player"`
+	StartPos *int            `json:"startpos"`
+	Modules  int             `json:"modules"`
+}
+
+// ReadObjectsJSON parses struct.json, droid.json or feature.json, objects are
+// returned in order of their keys in the file
+func ReadObjectsJSON(b []byte) ([]Object, error) {
+	all := map[string]json.RawMessage{}
+	if err := json.Unmarshal(b, &all); err != nil {
+		return nil, err
+	}
+	raw := map[string]jsonObject{}
+	for k, v := range all {
+		// skip non-object entries like format version
+		if len(v) == 0 || v[0] != '{' {
+			continue
+		}
+		var o jsonObject
+		if err := json.Unmarshal(v, &o); err != nil {
+			return nil, err
+		}
+		raw[k] = o
+	}
+	keys := make([]string, 0, len(raw))
+	for k := range raw {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return objectKeyLess(keys[i], keys[j])
+	})
+	ret := make([]Object, 0, len(raw))
+	for _, k := range keys {
+		o := raw[k]
+		obj := Object{
+			ID:       o.ID,
+			Name:     o.Name,
+			Template: o.Template,
+			Modules:  o.Modules,
+		}
+		if obj.Name == "" {
+			obj.Name = o.Template
+		}
+		if len(o.Position) > 0 {
+			obj.X = o.Position[0]
+		}
+		if len(o.Position) > 1 {
+			obj.Y = o.Position[1]
+		}
+		if len(o.Position) > 2 {
+			obj.Z = o.Position[2]
+		}
+		if len(o.Rotation) > 0 {
+			obj.Direction = uint16(o.Rotation[0])
+		}
+		switch {
+		case o.StartPos != nil:
+			obj.Player = *o.StartPos
+		case len(o.Player) > 0:
+			var p int
+			if err := json.Unmarshal(o.Player, &p); err == nil {
+				obj.Player = p
+			} else {
+				obj.Player = PlayerScavenger
+			}
+		}
+		ret = append(ret, obj)
+	}
+	return ret, nil
+}
+
+// objectKeyLess sorts keys like structure_2 before structure_10
+func objectKeyLess(a, b string) bool {
+	ai := strings.LastIndexByte(a, '_')
+	bi := strings.LastIndexByte(b, '_')
+	if ai >= 0 && bi >= 0 && a[:ai] == b[:bi] {
+		an, aerr := strconv.Atoi(a[ai+1:])
+		bn, berr := strconv.Atoi(b[bi+1:])
+		if aerr == nil && berr == nil {
+			return an < bn
+		}
+	}
+	return a < b
+}
+
+type bjoHeader struct {
+	Magic    [4]byte
+	Version  uint32
+	Quantity uint32
+}
+
+type bjoObject struct {
+	ID        uint32
+	X         uint32
+	Y         uint32
+	Z         uint32
+	Direction uint32
+	Player    uint32
+}
+
+// ReadObjectsBJO parses legacy struct.bjo (magic stru), dinit.bjo (dint) or
+// feat.bjo (feat). Only fields common to all versions are read, records are
+// skipped by their size so newer trailing fields do not matter.
+func ReadObjectsBJO(b []byte, magic string) ([]Object, error) {
+	r := bytes.NewReader(b)
+	var h bjoHeader
+	if err := readLE(r, &h); err != nil {
+		return nil, err
+	}
+	if string(h.Magic[:]) != magic {
+		return nil, ErrWrongMagic
+	}
+	if h.Quantity == 0 {
+		return []Object{}, nil
+	}
+	nameLen := 60
+	if h.Version <= 19 {
+		nameLen = 40
+	}
+	recordLen := r.Len() / int(h.Quantity)
+	if recordLen < nameLen+4*6 {
+		return nil, ErrTruncated
+	}
+	body := b[len(b)-r.Len():]
+	ret := make([]Object, 0, h.Quantity)
+	for i := 0; i < int(h.Quantity); i++ {
+		rec := body[i*recordLen : (i+1)*recordLen]
+		name := rec[:nameLen]
+		if n := bytes.IndexByte(name, 0); n >= 0 {
+			name = name[:n]
+		}
+		var o bjoObject
+		if err := readLE(bytes.NewReader(rec[nameLen:]), &o); err != nil {
+			return nil, err
+		}
+		ret = append(ret, Object{
+			ID:   o.ID,
+			Name: string(name),
+			X:    int(o.X),
+			Y:    int(o.Y),
+			Z:    int(o.Z),
+			// legacy files store direction in degrees
+			Direction: uint16(o.Direction % 360 * 65536 / 360),
+			Player:    int(o.Player),
+		})
+	}
+	return ret, nil
+}
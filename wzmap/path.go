@@ -0,0 +1,242 @@
+package wzmap
+
+import (
+	"container/heap"
+	"image"
+	"image/color"
+	"math"
+	"sort"
+
+	"github.com/maxsupermanhd/go-wz/stat"
+)
+
+type PathClass int
+
+const (
+	PathGround PathClass = iota
+	PathHover
+	PathVTOL
+)
+
+func (c PathClass) String() string {
+	switch c {
+	case PathGround:
+		return "ground"
+	case PathHover:
+		return "hover"
+	case PathVTOL:
+		return "vtol"
+	}
+	return "unknown"
+}
+
+// PathClassOf returns pathing class of propulsion type from propulsiontype.json
+func PathClassOf(name string, t *stat.PropulsionType) PathClass {
+	switch {
+	case t != nil && t.FlightName == "AIR":
+		return PathVTOL
+	case name == "Lift":
+		return PathVTOL
+	case name == "Hover" || name == "Propellor":
+		return PathHover
+	}
+	return PathGround
+}
+
+// Passable reports whether tile can be crossed, ground propulsions can not
+// cross water and cliffs, hover can not cross cliffs, VTOLs fly anywhere on map
+func (m *Map) Passable(x, y int, c PathClass) bool {
+	t, ok := m.Tile(x, y)
+	if !ok {
+		return false
+	}
+	switch m.TerrainType(t) {
+	case TerrainCliffFace:
+		return c == PathVTOL
+	case TerrainWater:
+		return c != PathGround
+	}
+	return true
+}
+
+// Unreachable is distance of tiles that can not be reached
+const Unreachable = -1
+
+// Distances returns travel distance in tiles from nearest of sources to every
+// tile of map (indexed like Tiles), diagonal moves cost sqrt 2 and can not cut
+// corners of impassable tiles. Only terrain is considered, objects do not block.
+func (m *Map) Distances(c PathClass, sources ...image.Point) []float64 {
+	ret := make([]float64, len(m.Tiles))
+	for i := range ret {
+		ret[i] = Unreachable
+	}
+	q := &pathQueue{}
+	for _, s := range sources {
+		if _, ok := m.Tile(s.X, s.Y); ok {
+			heap.Push(q, pathNode{s, 0})
+		}
+	}
+	done := make([]bool, len(m.Tiles))
+	for q.Len() > 0 {
+		n := heap.Pop(q).(pathNode)
+		i := n.p.Y*m.Width + n.p.X
+		if done[i] {
+			continue
+		}
+		done[i] = true
+		ret[i] = n.dist
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				if dx == 0 && dy == 0 {
+					continue
+				}
+				np := n.p.Add(image.Pt(dx, dy))
+				if !m.Passable(np.X, np.Y, c) || done[np.Y*m.Width+np.X] {
+					continue
+				}
+				cost := 1.0
+				if dx != 0 && dy != 0 {
+					if !m.Passable(n.p.X+dx, n.p.Y, c) || !m.Passable(n.p.X, n.p.Y+dy, c) {
+						continue
+					}
+					cost = math.Sqrt2
+				}
+				heap.Push(q, pathNode{np, n.dist + cost})
+			}
+		}
+	}
+	return ret
+}
+
+// Distance returns travel distance in tiles between two tiles or Unreachable
+func (m *Map) Distance(c PathClass, from, to image.Point) float64 {
+	if _, ok := m.Tile(to.X, to.Y); !ok {
+		return Unreachable
+	}
+	return m.Distances(c, from)[to.Y*m.Width+to.X]
+}
+
+type pathNode struct {
+	p    image.Point
+	dist float64
+}
+
+type pathQueue []pathNode
+
+func (q pathQueue) Len() int           { return len(q) }
+func (q pathQueue) Less(i, j int) bool { return q[i].dist < q[j].dist }
+func (q pathQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *pathQueue) Push(x any)        { *q = append(*q, x.(pathNode)) }
+func (q *pathQueue) Pop() any {
+	old := *q
+	ret := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return ret
+}
+
+type PathAnalysis struct {
+	Class string
+	// Propulsions lists propulsion types of this class
+	Propulsions []string
+	// StartToStart is distance between start positions of slots in tiles,
+	// Unreachable for slots without start position
+	StartToStart [][]float64
+	// StartToOil is distance from start position of slot to each Analysis.Oil,
+	// Unreachable for slots without start position
+	StartToOil [][]float64
+}
+
+// analyzePaths computes distances for every class of propulsion types, base
+// game propulsions are used without stats
+func (m *Map) analyzePaths(a *Analysis, s *stat.Stats) []PathAnalysis {
+	classes := map[PathClass][]string{}
+	if s != nil && len(s.PropulsionType) > 0 {
+		for name, t := range s.PropulsionType {
+			c := PathClassOf(name, t)
+			classes[c] = append(classes[c], name)
+		}
+	} else {
+		classes[PathGround] = []string{"Half-Tracked", "Legged", "Tracked", "Wheeled"}
+		classes[PathHover] = []string{"Hover", "Propellor"}
+		classes[PathVTOL] = []string{"Lift"}
+	}
+	ret := []PathAnalysis{}
+	for _, c := range []PathClass{PathGround, PathHover, PathVTOL} {
+		if len(classes[c]) == 0 {
+			continue
+		}
+		sort.Strings(classes[c])
+		pa := PathAnalysis{
+			Class:        c.String(),
+			Propulsions:  classes[c],
+			StartToStart: make([][]float64, len(a.Slots)),
+			StartToOil:   make([][]float64, len(a.Slots)),
+		}
+		for i, slot := range a.Slots {
+			pa.StartToStart[i] = unreachable(len(a.Slots))
+			pa.StartToOil[i] = unreachable(len(a.Oil))
+			if slot.Start == nil {
+				continue
+			}
+			d := m.Distances(c, *slot.Start)
+			at := func(p image.Point) float64 {
+				if _, ok := m.Tile(p.X, p.Y); !ok {
+					return Unreachable
+				}
+				return d[p.Y*m.Width+p.X]
+			}
+			for j, other := range a.Slots {
+				if other.Start != nil {
+					pa.StartToStart[i][j] = at(*other.Start)
+				}
+			}
+			for j, oil := range a.Oil {
+				pa.StartToOil[i][j] = at(oil)
+			}
+		}
+		ret = append(ret, pa)
+	}
+	return ret
+}
+
+func unreachable(n int) []float64 {
+	ret := make([]float64, n)
+	for i := range ret {
+		ret[i] = Unreachable
+	}
+	return ret
+}
+
+// PathOverlay renders terrain tinted by travel distance from nearest of
+// sources, from green near sources to red far away, unreachable tiles are
+// darkened. Image has one pixel per tile.
+func (m *Map) PathOverlay(c PathClass, sources ...image.Point) *image.RGBA {
+	ret := m.Terrain()
+	d := m.Distances(c, sources...)
+	farthest := 0.0
+	for _, v := range d {
+		if v > farthest {
+			farthest = v
+		}
+	}
+	for i, v := range d {
+		x, y := i%m.Width, i/m.Width
+		t := ret.RGBAAt(x, y)
+		if v == Unreachable {
+			ret.SetRGBA(x, y, color.RGBA{t.R / 3, t.G / 3, t.B / 3, 0xff})
+			continue
+		}
+		k := 0.0
+		if farthest > 0 {
+			k = v / farthest
+		}
+		over := color.RGBA{uint8(255 * k), uint8(255 * (1 - k)), 0, 0xff}
+		ret.SetRGBA(x, y, color.RGBA{
+			R: uint8((int(t.R) + int(over.R)) / 2),
+			G: uint8((int(t.G) + int(over.G)) / 2),
+			B: uint8((int(t.B) + int(over.B)) / 2),
+			A: 0xff,
+		})
+	}
+	return ret
+}
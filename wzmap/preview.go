@@ -0,0 +1,206 @@
+package wzmap
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+)
+
+// PlayerColors are default player colours of the game
+var PlayerColors = []color.RGBA{
+	{0x00, 0x8f, 0x00, 0xff}, // green
+	{0xff, 0xb0, 0x35, 0xff}, // orange
+	{0x9c, 0x9c, 0x9c, 0xff}, // grey
+	{0x24, 0x24, 0x24, 0xff}, // black
+	{0xbf, 0x00, 0x00, 0xff}, // red
+	{0x28, 0x28, 0xfd, 0xff}, // blue
+	{0xff, 0x46, 0xe1, 0xff}, // pink
+	{0x00, 0xc8, 0xc8, 0xff}, // cyan
+	{0xff, 0xff, 0x00, 0xff}, // yellow
+	{0x7b, 0x00, 0xae, 0xff}, // purple
+	{0xff, 0xff, 0xff, 0xff}, // white
+	{0x6e, 0x6e, 0xff, 0xff}, // bright blue
+	{0x41, 0xff, 0x55, 0xff}, // neon green
+	{0x8a, 0x08, 0x08, 0xff}, // infrared
+	{0x0f, 0x00, 0xff, 0xff}, // ultraviolet
+	{0x64, 0x40, 0x00, 0xff}, // brown
+}
+
+var (
+	ColorOil       = color.RGBA{0xff, 0xe6, 0x00, 0xff}
+	ColorScavenger = color.RGBA{0xc8, 0x00, 0x78, 0xff}
+	colorWater     = color.RGBA{0x2b, 0x4f, 0x9e, 0xff}
+	colorCliff     = color.RGBA{0x50, 0x42, 0x3a, 0xff}
+)
+
+// tileset ground colours used to tint height shading
+var tilesetColors = map[string]color.RGBA{
+	"arizona": {0xb0, 0x8f, 0x5f, 0xff},
+	"urban":   {0x8c, 0x8c, 0x8c, 0xff},
+	"rockies": {0xc8, 0xc8, 0xd2, 0xff},
+}
+
+// maximum height of old map format, used as lower bound of height normalization
+const maxLegacyHeight = 255 * ElevationScale
+
+// PreviewOptions control what is drawn over terrain by Preview
+type PreviewOptions struct {
+	// Scale is pixels per tile, values below 1 are treated as 1
+	Scale          int
+	StartPositions bool
+	Oil            bool
+	Scavengers     bool
+}
+
+var DefaultPreviewOptions = PreviewOptions{
+	Scale:          1,
+	StartPositions: true,
+	Oil:            true,
+	Scavengers:     true,
+}
+
+func (m *Map) maxHeight() int {
+	ret := maxLegacyHeight
+	for _, t := range m.Tiles {
+		if int(t.Height) > ret {
+			ret = int(t.Height)
+		}
+	}
+	return ret
+}
+
+// Heightmap renders grayscale image of tile heights, one pixel per tile
+func (m *Map) Heightmap() *image.Gray {
+	ret := image.NewGray(image.Rect(0, 0, m.Width, m.Height))
+	mh := m.maxHeight()
+	for i, t := range m.Tiles {
+		ret.Pix[i] = uint8(int(t.Height) * 255 / mh)
+	}
+	return ret
+}
+
+// Terrain renders height shaded terrain tinted by tileset with water and
+// cliff tiles highlighted, one pixel per tile
+func (m *Map) Terrain() *image.RGBA {
+	ret := image.NewRGBA(image.Rect(0, 0, m.Width, m.Height))
+	tint, ok := tilesetColors[m.Tileset()]
+	if !ok {
+		tint = tilesetColors["arizona"]
+	}
+	mh := m.maxHeight()
+	for y := 0; y < m.Height; y++ {
+		for x := 0; x < m.Width; x++ {
+			t := m.Tiles[y*m.Width+x]
+			// keep some brightness on lowest tiles so tint is still visible
+			shade := 64 + int(t.Height)*191/mh
+			var c color.RGBA
+			switch m.TerrainType(t) {
+			case TerrainWater:
+				c = colorWater
+			case TerrainCliffFace:
+				c = colorCliff
+			default:
+				c = tint
+			}
+			ret.SetRGBA(x, y, color.RGBA{
+				R: uint8(int(c.R) * shade / 255),
+				G: uint8(int(c.G) * shade / 255),
+				B: uint8(int(c.B) * shade / 255),
+				A: 0xff,
+			})
+		}
+	}
+	return ret
+}
+
+// Preview renders terrain with start positions, oil resources and scavenger structures
+func (m *Map) Preview(o PreviewOptions) *image.RGBA {
+	if o.Scale < 1 {
+		o.Scale = 1
+	}
+	ret := ScaleImage(m.Terrain(), o.Scale)
+	mark := func(obj Object, size int, c color.RGBA) {
+		px := obj.X * o.Scale / TileUnits
+		py := obj.Y * o.Scale / TileUnits
+		r := image.Rect(px-size/2, py-size/2, px-size/2+size, py-size/2+size)
+		draw.Draw(ret, r.Intersect(ret.Rect), image.NewUniform(c), image.Point{}, draw.Src)
+	}
+	if o.Scavengers {
+		for _, s := range m.Structures {
+			if m.IsScavenger(s.Player) {
+				mark(s, o.Scale, ColorScavenger)
+			}
+		}
+	}
+	if o.Oil {
+		for _, f := range m.Features {
+			if IsOilResource(f.Name) {
+				mark(f, o.Scale, ColorOil)
+			}
+		}
+	}
+	if o.StartPositions {
+		for p, pos := range m.StartPositions() {
+			if pos == nil {
+				continue
+			}
+			mark(*pos, o.Scale*2, PlayerColors[p%len(PlayerColors)])
+		}
+	}
+	return ret
+}
+
+// ScaleImage enlarges one pixel per tile image so each tile takes scale by scale pixels
+func ScaleImage(src *image.RGBA, scale int) *image.RGBA {
+	b := src.Bounds()
+	ret := image.NewRGBA(image.Rect(0, 0, b.Dx()*scale, b.Dy()*scale))
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			r := image.Rect(x*scale, y*scale, (x+1)*scale, (y+1)*scale)
+			draw.Draw(ret, r, image.NewUniform(src.RGBAAt(b.Min.X+x, b.Min.Y+y)), image.Point{}, draw.Src)
+		}
+	}
+	return ret
+}
+
+// IsScavenger reports whether objects of player belong to scavengers, old
+// maps place scavengers in slots after map players
+func (m *Map) IsScavenger(player int) bool {
+	if player == PlayerScavenger {
+		return true
+	}
+	p := m.Players()
+	return p > 0 && player >= p
+}
+
+func IsOilResource(name string) bool {
+	return strings.HasPrefix(name, "OilResource")
+}
+
+// StartPositions returns object marking start of each map player: command
+// center if there is one, otherwise first structure or droid. Players without
+// any objects have nil entry.
+func (m *Map) StartPositions() []*Object {
+	players := m.Players()
+	for _, l := range [][]Object{m.Structures, m.Droids} {
+		for _, o := range l {
+			if o.Player >= players && !m.IsScavenger(o.Player) {
+				players = o.Player + 1
+			}
+		}
+	}
+	ret := make([]*Object, players)
+	for _, l := range [][]Object{m.Structures, m.Droids} {
+		for i := range l {
+			o := &l[i]
+			if o.Player < 0 || o.Player >= players || m.IsScavenger(o.Player) {
+				continue
+			}
+			if ret[o.Player] == nil || (o.Name == "A0CommandCentre" && ret[o.Player].Name != "A0CommandCentre") {
+				ret[o.Player] = o
+			}
+		}
+	}
+	return ret
+}
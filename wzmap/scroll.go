@@ -0,0 +1,58 @@
+package wzmap
+
+import (
+	"bytes"
+	"image"
+	"regexp"
+	"strconv"
+)
+
+type gamHeader struct {
+	Magic      [4]byte
+	Version    uint32
+	GameTime   uint32
+	GameType   uint32
+	ScrollMinX int32
+	ScrollMinY int32
+	ScrollMaxX uint32
+	ScrollMaxY uint32
+}
+
+// ReadScrollLimits parses scroll limits (in tiles) from legacy .gam file
+func ReadScrollLimits(b []byte) (image.Rectangle, error) {
+	var h gamHeader
+	if err := readLE(bytes.NewReader(b), &h); err != nil {
+		return image.Rectangle{}, err
+	}
+	if string(h.Magic[:]) != "game" {
+		return image.Rectangle{}, ErrWrongMagic
+	}
+	return image.Rect(int(h.ScrollMinX), int(h.ScrollMinY), int(h.ScrollMaxX), int(h.ScrollMaxY)), nil
+}
+
+var scriptScrollLimitsRe = regexp.MustCompile(`setScrollLimits\(\s*(\d+)\s*,\s*(\d+)\s*,\s*(\d+)\s*,\s*(\d+)\s*\)`)
+
+// ScriptScrollLimits finds setScrollLimits call with constant arguments in map script
+func ScriptScrollLimits(script string) (image.Rectangle, bool) {
+	m := scriptScrollLimitsRe.FindStringSubmatch(script)
+	if m == nil {
+		return image.Rectangle{}, false
+	}
+	v := [4]int{}
+	for i := range v {
+		v[i], _ = strconv.Atoi(m[i+1])
+	}
+	return image.Rect(v[0], v[1], v[2], v[3]), true
+}
+
+// ScrollArea returns scroll limits clipped to map, whole map if limits are unset
+func (m *Map) ScrollArea() image.Rectangle {
+	full := image.Rect(0, 0, m.Width, m.Height)
+	if m.ScrollLimits.Empty() {
+		return full
+	}
+	if r := m.ScrollLimits.Intersect(full); !r.Empty() {
+		return r
+	}
+	return full
+}
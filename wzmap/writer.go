@@ -0,0 +1,243 @@
+package wzmap
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// terrain types file version written by editors
+const terrainTypesVersion = 8
+
+// WriteFile writes map as .wz archive, see Write
+func (m *Map) WriteFile(p string) error {
+	b, err := m.Bytes()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(p, b, 0644)
+}
+
+func (m *Map) Bytes() ([]byte, error) {
+	b := &bytes.Buffer{}
+	if err := m.Write(b); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// Write writes map as .wz archive in current flat layout with level.json and
+// objects in JSON format. Legacy maps get level.json made from their name,
+// player count and tileset, .lev and .gam files are not written and scroll
+// limits are set from game.js instead, unless map script sets them already.
+func (m *Map) Write(w io.Writer) error {
+	if m.Width <= 0 || m.Height <= 0 || m.Width > maxMapSide || m.Height > maxMapSide || len(m.Tiles) != m.Width*m.Height {
+		return ErrBadMapSize
+	}
+	z := zip.NewWriter(w)
+	files := map[string][]byte{}
+	level := m.Level
+	if level == nil {
+		level = m.legacyLevel()
+	}
+	b, err := level.marshal()
+	if err != nil {
+		return err
+	}
+	files["level.json"] = b
+	if script := m.scriptWithScrollLimits(); script != "" {
+		files["game.js"] = []byte(script)
+	}
+	files["game.map"] = m.gameMap()
+	if len(m.TerrainTypes) > 0 {
+		files["ttypes.ttp"] = m.terrainTypes()
+	}
+	for _, f := range []struct {
+		name, prefix string
+		objects      []Object
+	}{
+		{"struct.json", "structure", m.Structures},
+		{"droid.json", "droid", m.Droids},
+		{"feature.json", "feature", m.Features},
+	} {
+		b, err := m.objectsJSON(f.prefix, f.objects)
+		if err != nil {
+			return err
+		}
+		files[f.name] = b
+	}
+	// stable archive layout so same map gives same hash
+	names := make([]string, 0, len(files))
+	for k := range files {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fw, err := z.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate})
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(files[name]); err != nil {
+			return err
+		}
+	}
+	return z.Close()
+}
+
+func (m *Map) levelName() string {
+	if m.Name != "" {
+		return m.Name
+	}
+	return fmt.Sprintf("%dc-map", m.declaredPlayers())
+}
+
+// scriptWithScrollLimits appends setScrollLimits call to map script when
+// limits come from elsewhere (legacy .gam)
+func (m *Map) scriptWithScrollLimits() string {
+	if m.ScrollLimits.Empty() {
+		return m.Script
+	}
+	if _, ok := ScriptScrollLimits(m.Script); ok {
+		return m.Script
+	}
+	script := m.Script
+	if script != "" && !strings.HasSuffix(script, "\n") {
+		script += "\n"
+	}
+	r := m.ScrollArea()
+	return script + fmt.Sprintf("setScrollLimits(%d, %d, %d, %d);\n", r.Min.X, r.Min.Y, r.Max.X, r.Max.Y)
+}
+
+func (m *Map) gameMap() []byte {
+	version := uint32(39)
+	for _, t := range m.Tiles {
+		if t.Height%ElevationScale != 0 || t.Height > maxLegacyHeight {
+			version = 40
+			break
+		}
+	}
+	b := &bytes.Buffer{}
+	writeLE(b, gameMapHeader{Magic: [4]byte{'m', 'a', 'p', ' '}, Version: version, Width: uint32(m.Width), Height: uint32(m.Height)})
+	for _, t := range m.Tiles {
+		writeLE(b, t.Texture)
+		if version >= 40 {
+			writeLE(b, t.Height)
+		} else {
+			writeLE(b, uint8(t.Height/ElevationScale))
+		}
+	}
+	writeLE(b, []uint32{1, uint32(len(m.Gateways))})
+	writeLE(b, m.Gateways)
+	return b.Bytes()
+}
+
+func (m *Map) terrainTypes() []byte {
+	b := &bytes.Buffer{}
+	writeLE(b, terrainTypesHeader{Magic: [4]byte{'t', 't', 'y', 'p'}, Version: terrainTypesVersion, Count: uint32(len(m.TerrainTypes))})
+	writeLE(b, m.TerrainTypes)
+	return b.Bytes()
+}
+
+// declaredPlayers is player count map declares, when it declares none
+// occupied slots are counted. Slots with only scavenger (BaBa) objects are
+// scavengers placed in high slots by old editors and are not counted.
+func (m *Map) declaredPlayers() int {
+	if p := m.Players(); p > 0 {
+		return p
+	}
+	players := 0
+	for _, o := range m.StartPositions() {
+		if o != nil && !strings.Contains(o.Name, "BaBa") {
+			players++
+		}
+	}
+	return players
+}
+
+// legacyLevel makes level.json contents for map without one
+func (m *Map) legacyLevel() *Level {
+	tileset := m.Tileset()
+	if tileset == "" {
+		tileset = "arizona"
+	}
+	return &Level{
+		Name:    m.levelName(),
+		Type:    "skirmish",
+		Players: m.declaredPlayers(),
+		Tileset: tileset,
+	}
+}
+
+func (m *Map) objectsJSON(prefix string, objects []Object) ([]byte, error) {
+	out := map[string]map[string]any{}
+	for i, o := range objects {
+		v := map[string]any{
+			"position": []int{o.X, o.Y, o.Z},
+			"rotation": []int{int(o.Direction), 0, 0},
+		}
+		if o.ID != 0 {
+			v["id"] = o.ID
+		}
+		switch {
+		case o.Template != "":
+			v["template"] = o.Template
+		case prefix == "droid":
+			// legacy droids only have name, game expects template
+			v["template"] = o.Name
+		default:
+			v["name"] = o.Name
+		}
+		if prefix != "feature" {
+			if m.IsScavenger(o.Player) {
+				v["player"] = "scavenger"
+			} else {
+				v["startpos"] = o.Player
+			}
+		}
+		if o.Modules > 0 {
+			v["modules"] = o.Modules
+		}
+		out[fmt.Sprintf("%s_%d", prefix, i)] = v
+	}
+	return json.MarshalIndent(out, "", "\t")
+}
+
+func (l *Level) marshal() ([]byte, error) {
+	v := map[string]any{
+		"name":    l.Name,
+		"type":    l.Type,
+		"players": l.Players,
+		"tileset": l.Tileset,
+	}
+	if len(l.Authors) > 0 {
+		v["author"] = map[string]string{"name": l.Authors[0]}
+	}
+	if len(l.Authors) > 1 {
+		additional := []map[string]string{}
+		for _, a := range l.Authors[1:] {
+			additional = append(additional, map[string]string{"name": a})
+		}
+		v["additionalAuthors"] = additional
+	}
+	if l.License != "" {
+		v["license"] = l.License
+	}
+	if l.Created != "" {
+		v["created"] = map[string]string{"date": l.Created}
+	}
+	if l.Generator != "" {
+		v["generator"] = l.Generator
+	}
+	return json.MarshalIndent(v, "", "\t")
+}
+
+// writeLE only writes to bytes.Buffer which never fails
+func writeLE(w *bytes.Buffer, v any) {
+	binary.Write(w, binary.LittleEndian, v)
+}
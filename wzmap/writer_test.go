@@ -0,0 +1,210 @@
+package wzmap
+
+import (
+	"archive/zip"
+	"bytes"
+	"image"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func bjo(t *testing.T, magic string, objects []Object) []byte {
+	t.Helper()
+	b := &bytes.Buffer{}
+	writeLE(b, bjoHeader{Version: 20, Quantity: uint32(len(objects))})
+	copy(b.Bytes(), magic)
+	for _, o := range objects {
+		name := make([]byte, 60)
+		copy(name, o.Name)
+		b.Write(name)
+		writeLE(b, bjoObject{
+			ID:        o.ID,
+			X:         uint32(o.X),
+			Y:         uint32(o.Y),
+			Z:         uint32(o.Z),
+			Direction: uint32(o.Direction),
+			Player:    uint32(o.Player),
+		})
+		// trailing fields of newer versions
+		b.Write(make([]byte, 12))
+	}
+	return b.Bytes()
+}
+
+// legacyArchive makes 2c map in multiplay/maps layout with .bjo objects,
+// scavengers are in slot 6 like old editors put them
+func legacyArchive(t *testing.T) []byte {
+	t.Helper()
+	m := &Map{Width: 4, Height: 3, Tiles: make([]Tile, 12), TerrainTypes: []TerrainType{TerrainSand, TerrainWater, TerrainCliffFace}}
+	for i := range m.Tiles {
+		m.Tiles[i] = Tile{Texture: uint16(i%3) | TileXFlip, Height: uint16(i * 2 * ElevationScale)}
+	}
+	m.Gateways = []Gateway{{1, 1, 2, 1}}
+	dir := "multiplay/maps/2c-Test"
+	files := map[string][]byte{
+		dir + "/game.map":   m.gameMap(),
+		dir + "/ttypes.ttp": m.terrainTypes(),
+		dir + "/struct.bjo": bjo(t, "stru", []Object{
+			{ID: 1, Name: "A0CommandCentre", X: 192, Y: 192, Player: 0},
+			{ID: 2, Name: "A0CommandCentre", X: 320, Y: 192, Direction: 90, Player: 1},
+			{ID: 3, Name: "A0BaBaFactory", X: 192, Y: 320, Player: 6},
+		}),
+		dir + "/dinit.bjo": bjo(t, "dint", []Object{
+			{ID: 4, Name: "ConstructorDroid", X: 200, Y: 200, Direction: 180, Player: 0},
+		}),
+		dir + "/feat.bjo": bjo(t, "feat", []Object{
+			{ID: 5, Name: "OilResource", X: 448, Y: 64, Player: 0},
+		}),
+		"2c-Test.xplayers.lev": []byte("level   2c-Test-T1\nplayers 2\ntype    14\ndataset MULTI_CAM_2\ngame    \"multiplay/maps/2c-Test.gam\"\n"),
+	}
+	gam := &bytes.Buffer{}
+	writeLE(gam, gamHeader{Magic: [4]byte{'g', 'a', 'm', 'e'}, Version: 7, ScrollMinX: 1, ScrollMaxX: 4, ScrollMaxY: 3})
+	files[dir+".gam"] = gam.Bytes()
+
+	b := &bytes.Buffer{}
+	z := zip.NewWriter(b)
+	for name, content := range files {
+		w, err := z.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(content)
+	}
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestLegacyRoundTrip(t *testing.T) {
+	legacy, err := Open(legacyArchive(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !legacy.Legacy || legacy.Name != "2c-Test" {
+		t.Fatalf("fixture read as legacy %v name %q", legacy.Legacy, legacy.Name)
+	}
+	if legacy.ScrollLimits != image.Rect(1, 0, 4, 3) {
+		t.Errorf("scroll limits %v", legacy.ScrollLimits)
+	}
+	b, err := legacy.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	z, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, f := range z.File {
+		names = append(names, f.Name)
+	}
+	want := []string{"droid.json", "feature.json", "game.js", "game.map", "level.json", "struct.json", "ttypes.ttp"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("archive files %v, want %v", names, want)
+	}
+
+	m, err := Read(z)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Legacy {
+		t.Error("converted map is legacy")
+	}
+	if m.Width != legacy.Width || m.Height != legacy.Height || !reflect.DeepEqual(m.Tiles, legacy.Tiles) {
+		t.Error("tiles differ")
+	}
+	if !reflect.DeepEqual(m.TerrainTypes, legacy.TerrainTypes) || !reflect.DeepEqual(m.Gateways, legacy.Gateways) {
+		t.Error("terrain types or gateways differ")
+	}
+	if m.ScrollLimits != legacy.ScrollLimits {
+		t.Errorf("scroll limits %v, want %v", m.ScrollLimits, legacy.ScrollLimits)
+	}
+	wantLevel := &Level{Name: "2c-Test", Type: "skirmish", Players: 2, Tileset: "urban"}
+	if !reflect.DeepEqual(m.Level, wantLevel) {
+		t.Errorf("level %+v, want %+v", m.Level, wantLevel)
+	}
+
+	// scavenger slot is written as scavenger player
+	wantStructs := append([]Object{}, legacy.Structures...)
+	wantStructs[2].Player = PlayerScavenger
+	if !reflect.DeepEqual(m.Structures, wantStructs) {
+		t.Errorf("structures %+v, want %+v", m.Structures, wantStructs)
+	}
+	wantDroids := append([]Object{}, legacy.Droids...)
+	wantDroids[0].Template = "ConstructorDroid"
+	if !reflect.DeepEqual(m.Droids, wantDroids) {
+		t.Errorf("droids %+v, want %+v", m.Droids, wantDroids)
+	}
+	if !reflect.DeepEqual(m.Features, legacy.Features) {
+		t.Errorf("features %+v, want %+v", m.Features, legacy.Features)
+	}
+	if m.Droids[0].Direction != 32768 {
+		t.Errorf("droid direction %d", m.Droids[0].Direction)
+	}
+
+	// converted map is written back unchanged
+	again, err := m.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(again, b) {
+		t.Error("second conversion differs")
+	}
+}
+
+func TestDroidTemplateJSON(t *testing.T) {
+	m := &Map{Name: "2c-Test"}
+	b, err := m.objectsJSON("droid", []Object{{Name: "ConstructorDroid"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"template": "ConstructorDroid"`) || strings.Contains(string(b), `"name"`) {
+		t.Errorf("droid json %s", b)
+	}
+}
+
+func TestDeclaredPlayers(t *testing.T) {
+	objects := []Object{
+		{Name: "A0CommandCentre", Player: 0},
+		{Name: "A0CommandCentre", Player: 1},
+		{Name: "A0BaBaFactory", Player: 6},
+	}
+	tests := []struct {
+		m    *Map
+		want int
+	}{
+		{&Map{Name: "2c-Test", Structures: objects}, 2},
+		{&Map{Name: "Test", Structures: objects}, 2},
+		{&Map{Name: "Test", Levels: []LegacyLevel{{Players: 4}}, Structures: objects}, 4},
+		{&Map{Level: &Level{Players: 8}, Structures: objects}, 8},
+	}
+	for _, tt := range tests {
+		if got := tt.m.declaredPlayers(); got != tt.want {
+			t.Errorf("%q: got %d players, want %d", tt.m.Name, got, tt.want)
+		}
+	}
+}
+
+func TestScriptWithScrollLimits(t *testing.T) {
+	tests := []struct {
+		script string
+		limits image.Rectangle
+		want   string
+	}{
+		{"", image.Rectangle{}, ""},
+		{"", image.Rect(2, 3, 60, 61), "setScrollLimits(2, 3, 60, 61);\n"},
+		{"include(\"x.js\");", image.Rect(0, 0, 64, 64), "include(\"x.js\");\nsetScrollLimits(0, 0, 64, 64);\n"},
+		// limits beyond map are clipped
+		{"", image.Rect(0, 0, 100, 64), "setScrollLimits(0, 0, 64, 64);\n"},
+		// script setting limits itself is kept
+		{"setScrollLimits(1, 1, 10, 10);\n", image.Rect(1, 1, 10, 10), "setScrollLimits(1, 1, 10, 10);\n"},
+	}
+	for _, tt := range tests {
+		m := &Map{Width: 64, Height: 64, Script: tt.script, ScrollLimits: tt.limits}
+		if got := m.scriptWithScrollLimits(); got != tt.want {
+			t.Errorf("script %q limits %v: got %q, want %q", tt.script, tt.limits, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package wzmap
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"image"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrNoGameMap          = errors.New("archive does not contain game.map")
+	ErrWrongMagic         = errors.New("wrong magic")
+	ErrUnsupportedVersion = errors.New("unsupported version")
+	ErrTruncated          = errors.New("data is truncated")
+	ErrBadMapSize         = errors.New("bad map size")
+)
+
+// world units per tile
+const TileUnits = 128
+
+// maxPlayers is most player slots game supports on a map
+const maxPlayers = 10
+
+type Map struct {
+	// Name is name of map directory inside archive
+	Name         string
+	Width        int
+	Height       int
+	Tiles        []Tile
+	TerrainTypes []TerrainType
+	Gateways     []Gateway
+	Structures   []Object
+	Droids       []Object
+	Features     []Object
+	Level        *Level
+	Levels       []LegacyLevel
+	Script       string
+	// ScrollLimits are in tiles, empty when map does not set them
+	ScrollLimits image.Rectangle
+	// Legacy is true when objects were read from .bjo files
+	Legacy bool
+}
+
+// Tile returns tile at tile coordinates, ok is false when they are off the map
+func (m *Map) Tile(x, y int) (Tile, bool) {
+	if x < 0 || y < 0 || x >= m.Width || y >= m.Height {
+		return Tile{}, false
+	}
+	return m.Tiles[y*m.Width+x], true
+}
+
+// TerrainType returns terrain type of tile texture, unknown textures are TerrainSand like in game
+func (m *Map) TerrainType(t Tile) TerrainType {
+	if id := t.TextureID(); id < len(m.TerrainTypes) {
+		return m.TerrainTypes[id]
+	}
+	return TerrainSand
+}
+
+// Players returns number of player slots map was made for
+func (m *Map) Players() int {
+	if m.Level != nil && m.Level.Players > 0 {
+		return m.Level.Players
+	}
+	for _, l := range m.Levels {
+		if l.Players > 0 {
+			return l.Players
+		}
+	}
+	return namePlayers(m.Name)
+}
+
+// namePlayers parses player count from map names like 2c-Startup
+func namePlayers(name string) int {
+	i := strings.IndexByte(name, '-')
+	if i < 2 || (name[i-1] != 'c' && name[i-1] != 'C') {
+		return 0
+	}
+	n, err := strconv.Atoi(name[:i-1])
+	if err != nil || n <= 0 || n > maxPlayers {
+		return 0
+	}
+	return n
+}
+
+// Tileset returns arizona, urban or rockies
+func (m *Map) Tileset() string {
+	if m.Level != nil && m.Level.Tileset != "" {
+		return m.Level.Tileset
+	}
+	for _, l := range m.Levels {
+		if t := l.Tileset(); t != "" {
+			return t
+		}
+	}
+	return ""
+}
+
+func OpenFile(p string) (*Map, error) {
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	return Open(b)
+}
+
+// Open parses .wz map archive, for example Replay.EmbeddedMap or mapsdatabase.FetchMapBlob result
+func Open(b []byte) (*Map, error) {
+	z, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		return nil, err
+	}
+	return Read(z)
+}
+
+func Read(z *zip.Reader) (*Map, error) {
+	files := map[string]*zip.File{}
+	var gamemap *zip.File
+	for _, f := range z.File {
+		name := strings.ReplaceAll(f.Name, "\\", "/")
+		files[strings.ToLower(name)] = f
+		if strings.EqualFold(path.Base(name), "game.map") && gamemap == nil {
+			gamemap = f
+		}
+	}
+	if gamemap == nil {
+		return nil, ErrNoGameMap
+	}
+	dir := path.Dir(strings.ReplaceAll(gamemap.Name, "\\", "/"))
+	read := func(name string) ([]byte, error) {
+		f, ok := files[strings.ToLower(path.Join(dir, name))]
+		if !ok {
+			return nil, nil
+		}
+		return readZipFile(f)
+	}
+
+	m := &Map{}
+	if dir != "." {
+		m.Name = path.Base(dir)
+	}
+	b, err := read("game.map")
+	if err != nil {
+		return nil, err
+	}
+	if err := m.readGameMap(b); err != nil {
+		return nil, err
+	}
+	if b, err = read("ttypes.ttp"); err != nil {
+		return nil, err
+	} else if b != nil {
+		if m.TerrainTypes, err = ReadTerrainTypes(b); err != nil {
+			return nil, err
+		}
+	}
+	if err := m.readObjects(read); err != nil {
+		return nil, err
+	}
+
+	for _, f := range z.File {
+		name := strings.ToLower(strings.ReplaceAll(f.Name, "\\", "/"))
+		var b []byte
+		switch {
+		case path.Base(name) == "level.json" && m.Level == nil:
+			if b, err = readZipFile(f); err != nil {
+				return nil, err
+			}
+			if m.Level, err = ReadLevel(b); err != nil {
+				return nil, err
+			}
+		case strings.HasSuffix(name, ".lev"):
+			if b, err = readZipFile(f); err != nil {
+				return nil, err
+			}
+			m.Levels = append(m.Levels, ReadLegacyLevels(b)...)
+		}
+	}
+	// map script lives next to map directory (multiplay/maps/name.js) or inside of it
+	for _, name := range []string{dir + ".js", path.Join(dir, "game.js")} {
+		if f, ok := files[strings.ToLower(name)]; ok {
+			if b, err = readZipFile(f); err != nil {
+				return nil, err
+			}
+			m.Script = string(b)
+			break
+		}
+	}
+	if r, ok := ScriptScrollLimits(m.Script); ok {
+		m.ScrollLimits = r
+	} else if f, ok := files[strings.ToLower(dir+".gam")]; ok {
+		if b, err = readZipFile(f); err != nil {
+			return nil, err
+		}
+		if m.ScrollLimits, err = ReadScrollLimits(b); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	r, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}